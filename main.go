@@ -1,16 +1,21 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"math"
 	"net/http"
+	"os"
 	"sort"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/joeabbey/ferret/pkg/aws"
 	"github.com/joeabbey/ferret/pkg/ferret"
 
 	ui "github.com/gizak/termui/v3"
@@ -18,40 +23,37 @@ import (
 )
 
 func main() {
-	prefix := "https://ec2."
-	suffix := ".amazonaws.com/ping"
+	baseline := flag.Duration("baseline", 0, "mark endpoints whose average latency exceeds this duration")
+	slow := flag.Duration("slow", 0, "highlight iterations whose total request time exceeds this duration (default: the run's p90)")
+	jsonOutput := flag.Bool("json", false, "skip the UI and print the ranked endpoints as JSON")
+	flag.Parse()
+
+	var provider ferret.EndpointProvider = aws.GetRegions()
+	endpoints := provider.Endpoints()
 	iterations := 10
-	endpoints := []string{
-		"ap-northeast-1",
-		"ap-northeast-2",
-		"ap-northeast-3",
-		"ap-south-1",
-		"ap-southeast-1",
-		"ap-southeast-2",
-		"ca-central-1",
-		"eu-central-1",
-		"eu-north-1",
-		"eu-west-1",
-		"eu-west-2",
-		"eu-west-3",
-		"sa-east-1",
-		"us-east-1",
-		"us-east-2",
-		"us-west-1",
-		"us-west-2",
-	}
-	ep := startUI(prefix, endpoints, suffix, iterations)
+
+	if *jsonOutput {
+		ranked := rankEndpoints(endpoints, iterations, *baseline)
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(ranked); err != nil {
+			log.Fatalf("failed to encode results: %v", err)
+		}
+		return
+	}
+
+	ep := startUI(endpoints, iterations, *baseline, *slow)
 	fmt.Printf("%s\n", ep)
 
 }
 
-func startUI(prefix string, endpoints []string, suffix string, iterations int) string {
+func startUI(endpoints []ferret.Endpoint, iterations int, baseline time.Duration, slow time.Duration) string {
 	if err := ui.Init(); err != nil {
 		log.Fatalf("failed to initialize termui: %v", err)
 	}
 	defer ui.Close()
 
-	ep := findNearestEndpoint(prefix, endpoints, suffix, iterations)
+	ep := findNearestEndpoint(endpoints, iterations, baseline, slow)
 
 	uiEvents := ui.PollEvents()
 
@@ -60,7 +62,7 @@ func startUI(prefix string, endpoints []string, suffix string, iterations int) s
 		e := <-uiEvents
 		switch e.ID {
 		case "r":
-			ep = findNearestEndpoint(prefix, endpoints, suffix, iterations)
+			ep = findNearestEndpoint(endpoints, iterations, baseline, slow)
 		case "q", "<C-c>":
 			done = true
 		}
@@ -68,13 +70,7 @@ func startUI(prefix string, endpoints []string, suffix string, iterations int) s
 	return ep
 }
 
-func findNearestEndpoint(prefix string, endpoints []string, suffix string, iterations int) string {
-	const maxConcurrent = 64
-	sem := make(chan bool, maxConcurrent)
-	var mtx sync.Mutex
-	var wg sync.WaitGroup
-	var results [][]time.Duration
-
+func findNearestEndpoint(endpoints []ferret.Endpoint, iterations int, baseline time.Duration, slow time.Duration) string {
 	tableView := widgets.NewTable()
 	tableView.ColumnWidths = []int{15, 7}
 	tableView.Rows = append(tableView.Rows, []string{
@@ -86,87 +82,216 @@ func findNearestEndpoint(prefix string, endpoints []string, suffix string, itera
 		tableView.Rows[0] = append(tableView.Rows[0], strconv.Itoa(i+1))
 	}
 
+	columns, width := iterations+2, (iterations*8)+27
+	if baseline > 0 {
+		tableView.Rows[0] = append(tableView.Rows[0], "ok")
+		columns, width = columns+1, width+8
+	}
+
 	for e, endpoint := range endpoints {
-		tableView.Rows = append(tableView.Rows, make([]string, iterations+2))
-		tableView.Rows[e+1][0] = endpoint
-		results = append(results, make([]time.Duration, iterations))
+		tableView.Rows = append(tableView.Rows, make([]string, columns))
+		tableView.Rows[e+1][0] = endpoint.ID
 		tableView.ColumnWidths = append(tableView.ColumnWidths, 7)
 	}
 
-	tableView.SetRect(2, 2, (iterations*8)+27, len(endpoints)*2+1)
+	tableView.SetRect(2, 2, width, len(endpoints)*2+1)
 	tableView.TextStyle = ui.NewStyle(ui.ColorWhite)
 	tableView.TextAlignment = ui.AlignCenter
 	ui.Render(tableView)
 
+	var samples []iterationSample
+	results := measureEndpoints(endpoints, iterations, func(e int, iter int, f *ferret.Ferret, err error) {
+		if err == nil {
+			samples = append(samples, iterationSample{endpoint: e, iteration: iter, ferret: f})
+		}
+		tableView.Rows[e+1][iter+2] = iterationCell(f.ConnDuration(), err, slow > 0 && f.IsSlow(slow))
+		ui.Render(tableView)
+	})
+	if slow == 0 {
+		markSlowIterations(tableView, samples)
+	}
+
+	computeAverages(results, tableView)
+	sortByAverage(tableView)
+	colorizeRows(tableView)
+	if baseline > 0 {
+		markBaseline(tableView, baseline)
+	}
+	ui.Render(tableView)
+
+	ep := tableView.Rows[1][0]
+	return ep
+}
+
+//measureEndpoints - Measure every endpoint iterations times, report is called (serialized) as each result arrives
+func measureEndpoints(endpoints []ferret.Endpoint, iterations int, report func(e int, iter int, f *ferret.Ferret, err error)) [][]time.Duration {
+	const maxConcurrent = 64
+	sem := make(chan bool, maxConcurrent)
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+
+	results := make([][]time.Duration, len(endpoints))
+	for e := range endpoints {
+		results[e] = make([]time.Duration, iterations)
+	}
+
 	for e, endpoint := range endpoints {
 		for iter := 0; iter < iterations; iter++ {
 			sem <- true
-			go func(iter int, e int, endpoint string) {
-				wg.Add(1)
+			wg.Add(1)
+			go func(iter int, e int, endpoint ferret.Endpoint) {
 				defer wg.Done()
 				defer func() { <-sem }()
 
-				d, err := measureDuration(prefix + endpoint + suffix)
+				f, err := measureDuration(endpoint.URL)
 				mtx.Lock()
-				results[e][iter] = d
-				if err != nil {
-					tableView.Rows[e+1][iter+2] = "???"
-				} else {
-					tableView.Rows[e+1][iter+2] = d.Truncate(time.Millisecond).String()
+				if err == nil {
+					results[e][iter] = f.ConnDuration()
 				}
-				ui.Render(tableView)
+				report(e, iter, f, err)
 				mtx.Unlock()
 
 			}(iter, e, endpoint)
 		}
 	}
 
-	for i := 0; i < cap(sem); i++ {
-		sem <- true
+	wg.Wait()
+	return results
+}
+
+type endpointResult struct {
+	Endpoint       string  `json:"endpoint"`
+	Name           string  `json:"name,omitempty"`
+	AverageMs      float64 `json:"average_ms"`
+	Errors         int     `json:"errors"`
+	WithinBaseline *bool   `json:"within_baseline,omitempty"`
+}
+
+//rankEndpoints - Measure the endpoints without the UI and rank them by average latency
+func rankEndpoints(endpoints []ferret.Endpoint, iterations int, baseline time.Duration) []endpointResult {
+	failures := make([]int, len(endpoints))
+	results := measureEndpoints(endpoints, iterations, func(e int, iter int, f *ferret.Ferret, err error) {
+		if err != nil {
+			failures[e]++
+		}
+	})
+
+	ranked := make([]endpointResult, len(endpoints))
+	averages := make(map[string]time.Duration, len(endpoints))
+	for e, endpoint := range endpoints {
+		avg := averageDuration(results[e])
+		averages[endpoint.ID] = avg
+		ranked[e] = endpointResult{
+			Endpoint:  endpoint.ID,
+			Name:      endpoint.Name,
+			AverageMs: float64(avg) / float64(time.Millisecond),
+			Errors:    failures[e],
+		}
+		if baseline > 0 {
+			within := failures[e] < iterations && withinBaseline(avg, baseline)
+			ranked[e].WithinBaseline = &within
+		}
 	}
 
-	wg.Wait()
+	sort.SliceStable(ranked, func(i, j int) bool {
+		//Endpoints which never answered rank last
+		failedI, failedJ := ranked[i].Errors == iterations, ranked[j].Errors == iterations
+		if failedI != failedJ {
+			return failedJ
+		}
+		return averages[ranked[i].Endpoint] < averages[ranked[j].Endpoint]
+	})
+	return ranked
+}
 
-	computeAverages(results, tableView)
-	sortByAverage(tableView)
-	colorizeRows(tableView)
-	ui.Render(tableView)
+//iterationCell - Format one measured connect time, in red when the iteration was slow
+func iterationCell(d time.Duration, err error, slow bool) string {
+	if err != nil {
+		return "???"
+	}
+	cell := d.Truncate(time.Millisecond).String()
+	if slow {
+		return "[" + cell + "](fg:red)"
+	}
+	return cell
+}
 
-	ep := tableView.Rows[1][0]
-	return ep
+//iterationSample - The Ferret which timed one successful iteration of an endpoint
+type iterationSample struct {
+	endpoint  int
+	iteration int
+	ferret    *ferret.Ferret
 }
 
-func measureDuration(url string) (time.Duration, error) {
+//markSlowIterations - Highlight the iterations whose total request time exceeds the p90 of the run
+func markSlowIterations(tableView *widgets.Table, samples []iterationSample) {
+	totals := make([]time.Duration, len(samples))
+	for i, s := range samples {
+		totals[i] = s.ferret.Duration()
+	}
+	threshold := percentile(totals, 90)
+	for _, s := range samples {
+		tableView.Rows[s.endpoint+1][s.iteration+2] = iterationCell(s.ferret.ConnDuration(), nil, s.ferret.IsSlow(threshold))
+	}
+}
+
+//percentile - Get the p-th percentile (nearest rank) of samples, zero if there are none
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+//measureDuration - Fetch url through a new Ferret and return it for its timings
+func measureDuration(url string) (*ferret.Ferret, error) {
 	f := ferret.NewFerret()
 	client := &http.Client{Transport: f}
 
 	resp, err := client.Get(url)
 	if err != nil {
-		return time.Duration(0), err
+		return f, err
 	}
 	defer resp.Body.Close()
 
 	output := ioutil.Discard
 	io.Copy(output, resp.Body)
 
-	return f.ConnDuration(), nil
+	return f, nil
 }
 
 func computeAverages(results [][]time.Duration, tableView *widgets.Table) {
 	for i, row := range results {
-		var sum time.Duration
-		for _, result := range row {
-			if result != time.Duration(0) {
-				sum = sum + result
-			}
-		}
+		average := averageDuration(row)
+		tableView.Rows[i+1][1] = average.Truncate(time.Millisecond).String()
+	}
+}
 
-		//No this isn't a duration, but it makes the types match
-		iterations := time.Duration(len(row) - 1)
-		average := sum / iterations
+//averageDuration - Average the successful (nonzero) samples in row, zero if there were none
+func averageDuration(row []time.Duration) time.Duration {
+	var sum time.Duration
+	var successes int
+	for _, result := range row {
+		if result != time.Duration(0) {
+			sum = sum + result
+			successes++
+		}
+	}
 
-		tableView.Rows[i+1][1] = average.Truncate(time.Millisecond).String()
+	if successes == 0 {
+		return 0
 	}
+	//No this isn't a duration, but it makes the types match
+	return sum / time.Duration(successes)
 }
 
 func sortByAverage(tableView *widgets.Table) {
@@ -204,3 +329,29 @@ func colorizeRows(tableView *widgets.Table) {
 		}
 	}
 }
+
+func withinBaseline(avg time.Duration, baseline time.Duration) bool {
+	return avg <= baseline
+}
+
+//markBaseline - Fill the trailing status column; endpoints which never answered are never within the baseline
+func markBaseline(tableView *widgets.Table, baseline time.Duration) {
+	for i := 1; i < len(tableView.Rows); i++ {
+		row := tableView.Rows[i]
+		avg, err := time.ParseDuration(row[1])
+		if err == nil && !allFailed(row[2:len(row)-1]) && withinBaseline(avg, baseline) {
+			row[len(row)-1] = "[✓](fg:green)"
+		} else {
+			row[len(row)-1] = "[✗](fg:red)"
+		}
+	}
+}
+
+func allFailed(iterations []string) bool {
+	for _, cell := range iterations {
+		if cell != "???" {
+			return false
+		}
+	}
+	return true
+}
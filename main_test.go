@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gizak/termui/v3/widgets"
+	"github.com/joeabbey/ferret/pkg/ferret"
+)
+
+func TestWithinBaseline(t *testing.T) {
+	baseline := 100 * time.Millisecond
+	cases := []struct {
+		avg  time.Duration
+		want bool
+	}{
+		{50 * time.Millisecond, true},
+		{100 * time.Millisecond, true},
+		{101 * time.Millisecond, false},
+	}
+	for _, c := range cases {
+		if got := withinBaseline(c.avg, baseline); got != c.want {
+			t.Errorf("withinBaseline(%v, %v) = %v, want %v", c.avg, baseline, got, c.want)
+		}
+	}
+}
+
+func TestMarkBaseline(t *testing.T) {
+	tableView := widgets.NewTable()
+	tableView.Rows = [][]string{
+		{"Endpoint", "avg", "1", "2", "ok"},
+		{"fast", "50ms", "40ms", "60ms", ""},
+		{"slow", "300ms", "300ms", "300ms", ""},
+		{"down", "0s", "???", "???", ""},
+		{"flaky", "80ms", "???", "80ms", ""},
+	}
+
+	markBaseline(tableView, 100*time.Millisecond)
+
+	want := map[string]string{
+		"fast":  "[✓](fg:green)",
+		"slow":  "[✗](fg:red)",
+		"down":  "[✗](fg:red)",
+		"flaky": "[✓](fg:green)",
+	}
+	for _, row := range tableView.Rows[1:] {
+		if got := row[len(row)-1]; got != want[row[0]] {
+			t.Errorf("%s: status = %q, want %q", row[0], got, want[row[0]])
+		}
+	}
+}
+
+func TestIterationCell(t *testing.T) {
+	cases := []struct {
+		d    time.Duration
+		err  error
+		slow bool
+		want string
+	}{
+		{50 * time.Millisecond, nil, false, "50ms"},
+		{150*time.Millisecond + 300*time.Microsecond, nil, true, "[150ms](fg:red)"},
+		{0, errors.New("refused"), true, "???"},
+	}
+	for _, c := range cases {
+		if got := iterationCell(c.d, c.err, c.slow); got != c.want {
+			t.Errorf("iterationCell(%v, %v, %v) = %q, want %q", c.d, c.err, c.slow, got, c.want)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	samples := make([]time.Duration, 10)
+	for i := range samples {
+		samples[i] = time.Duration(10-i) * time.Millisecond
+	}
+	cases := []struct {
+		samples []time.Duration
+		p       float64
+		want    time.Duration
+	}{
+		{samples, 90, 9 * time.Millisecond},
+		{samples, 50, 5 * time.Millisecond},
+		{samples, 100, 10 * time.Millisecond},
+		{samples, 0, 1 * time.Millisecond},
+		{[]time.Duration{7 * time.Millisecond}, 90, 7 * time.Millisecond},
+		{nil, 90, 0},
+	}
+	for _, c := range cases {
+		if got := percentile(c.samples, c.p); got != c.want {
+			t.Errorf("percentile(%v, %v) = %v, want %v", c.samples, c.p, got, c.want)
+		}
+	}
+}
+
+func TestMarkSlowIterations(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	tableView := widgets.NewTable()
+	tableView.Rows = [][]string{{"Endpoint", "avg"}, {"local", ""}}
+	var samples []iterationSample
+	for iter := 0; iter < 10; iter++ {
+		url := srv.URL + "/fast"
+		if iter == 3 {
+			url = srv.URL + "/slow"
+		}
+		f, err := measureDuration(url)
+		if err != nil {
+			t.Fatalf("measureDuration(%s): %v", url, err)
+		}
+		tableView.Rows[0] = append(tableView.Rows[0], strconv.Itoa(iter+1))
+		tableView.Rows[1] = append(tableView.Rows[1], "")
+		samples = append(samples, iterationSample{endpoint: 0, iteration: iter, ferret: f})
+	}
+
+	markSlowIterations(tableView, samples)
+
+	//Only the one iteration above the p90 of the run is highlighted
+	for iter, cell := range tableView.Rows[1][2:] {
+		if highlighted := strings.HasSuffix(cell, "(fg:red)"); highlighted != (iter == 3) {
+			t.Errorf("iteration %d: cell %q, highlighted = %v, want %v", iter+1, cell, highlighted, iter == 3)
+		}
+	}
+}
+
+func TestAverageDuration(t *testing.T) {
+	cases := []struct {
+		row  []time.Duration
+		want time.Duration
+	}{
+		{[]time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond}, 20 * time.Millisecond},
+		{[]time.Duration{0, 20 * time.Millisecond, 40 * time.Millisecond}, 30 * time.Millisecond},
+		{[]time.Duration{0, 0}, 0},
+		{nil, 0},
+	}
+	for _, c := range cases {
+		if got := averageDuration(c.row); got != c.want {
+			t.Errorf("averageDuration(%v) = %v, want %v", c.row, got, c.want)
+		}
+	}
+}
+
+func TestRankEndpoints(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	dead.Close()
+
+	endpoints := []ferret.Endpoint{
+		{ID: "dead", Name: "Dead", URL: dead.URL},
+		{ID: "local", Name: "Local", URL: srv.URL},
+	}
+	const iterations = 3
+	ranked := rankEndpoints(endpoints, iterations, time.Second)
+
+	if len(ranked) != 2 {
+		t.Fatalf("rankEndpoints returned %d results, want 2", len(ranked))
+	}
+	local, down := ranked[0], ranked[1]
+	if local.Endpoint != "local" || down.Endpoint != "dead" {
+		t.Fatalf("ranking = [%s %s], want [local dead]", local.Endpoint, down.Endpoint)
+	}
+	if local.Errors != 0 || local.AverageMs <= 0 {
+		t.Errorf("local = %+v, want no errors and a positive average", local)
+	}
+	if local.WithinBaseline == nil || !*local.WithinBaseline {
+		t.Errorf("local.WithinBaseline = %v, want true", local.WithinBaseline)
+	}
+	if down.Errors != iterations || down.AverageMs != 0 {
+		t.Errorf("dead = %+v, want %d errors and a zero average", down, iterations)
+	}
+	if down.WithinBaseline == nil || *down.WithinBaseline {
+		t.Errorf("dead.WithinBaseline = %v, want false", down.WithinBaseline)
+	}
+}
@@ -0,0 +1,50 @@
+package aws
+
+import (
+	"github.com/joeabbey/ferret/pkg/ferret"
+)
+
+//Regions - AWS regions, measured through their EC2 ping endpoint
+type Regions []Region
+
+//Region - An AWS region
+type Region struct {
+	ID   string
+	Name string
+}
+
+//GetRegions - Get the AWS regions ferret knows about
+func GetRegions() Regions {
+	return Regions{
+		{"ap-northeast-1", "Asia Pacific (Tokyo)"},
+		{"ap-northeast-2", "Asia Pacific (Seoul)"},
+		{"ap-northeast-3", "Asia Pacific (Osaka)"},
+		{"ap-south-1", "Asia Pacific (Mumbai)"},
+		{"ap-southeast-1", "Asia Pacific (Singapore)"},
+		{"ap-southeast-2", "Asia Pacific (Sydney)"},
+		{"ca-central-1", "Canada (Central)"},
+		{"eu-central-1", "Europe (Frankfurt)"},
+		{"eu-north-1", "Europe (Stockholm)"},
+		{"eu-west-1", "Europe (Ireland)"},
+		{"eu-west-2", "Europe (London)"},
+		{"eu-west-3", "Europe (Paris)"},
+		{"sa-east-1", "South America (Sao Paulo)"},
+		{"us-east-1", "US East (N. Virginia)"},
+		{"us-east-2", "US East (Ohio)"},
+		{"us-west-1", "US West (N. California)"},
+		{"us-west-2", "US West (Oregon)"},
+	}
+}
+
+//Endpoints - Get the EC2 ping endpoint of every region
+func (r Regions) Endpoints() []ferret.Endpoint {
+	endpoints := make([]ferret.Endpoint, len(r))
+	for i, region := range r {
+		endpoints[i] = ferret.Endpoint{
+			ID:   region.ID,
+			Name: region.Name,
+			URL:  "https://ec2." + region.ID + ".amazonaws.com/ping",
+		}
+	}
+	return endpoints
+}
@@ -0,0 +1,34 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/joeabbey/ferret/pkg/ferret"
+)
+
+func TestRegionsEndpoints(t *testing.T) {
+	regions := GetRegions()
+	var provider ferret.EndpointProvider = regions
+	endpoints := provider.Endpoints()
+
+	if len(endpoints) != len(regions) {
+		t.Fatalf("got %d endpoints for %d regions", len(endpoints), len(regions))
+	}
+	for i, e := range endpoints {
+		if e.ID != regions[i].ID || e.Name != regions[i].Name {
+			t.Errorf("endpoint %d = (%q, %q), want (%q, %q)", i, e.ID, e.Name, regions[i].ID, regions[i].Name)
+		}
+		if want := "https://ec2." + regions[i].ID + ".amazonaws.com/ping"; e.URL != want {
+			t.Errorf("%s: URL = %q, want %q", e.ID, e.URL, want)
+		}
+	}
+}
+
+func TestRegionsEndpointsExample(t *testing.T) {
+	endpoints := Regions{{"us-east-1", "US East (N. Virginia)"}}.Endpoints()
+
+	want := ferret.Endpoint{ID: "us-east-1", Name: "US East (N. Virginia)", URL: "https://ec2.us-east-1.amazonaws.com/ping"}
+	if len(endpoints) != 1 || endpoints[0] != want {
+		t.Errorf("Endpoints() = %+v, want [%+v]", endpoints, want)
+	}
+}
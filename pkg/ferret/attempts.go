@@ -0,0 +1,48 @@
+package ferret
+
+import (
+	"time"
+)
+
+//ConnectAttempt - One dial attempt to one address of a (possibly multi-address) host
+type ConnectAttempt struct {
+	Addr     string
+	Duration time.Duration
+	Err      error
+}
+
+//ConnectAttempts - Get every connect attempt made for the last request, in completion order.
+//More than one attempt means earlier addresses failed or were raced (Happy Eyeballs)
+func (f *Ferret) ConnectAttempts() []ConnectAttempt {
+	f.attemptsMtx.Lock()
+	defer f.attemptsMtx.Unlock()
+	return append([]ConnectAttempt(nil), f.connectAttempts...)
+}
+
+func (f *Ferret) resetConnectAttempts() {
+	f.attemptsMtx.Lock()
+	defer f.attemptsMtx.Unlock()
+	f.attemptStarts = nil
+	f.connectAttempts = nil
+}
+
+//connectStart - httptrace hook, may be called concurrently when dialing in parallel
+func (f *Ferret) connectStart(network, addr string) {
+	f.attemptsMtx.Lock()
+	defer f.attemptsMtx.Unlock()
+	if f.attemptStarts == nil {
+		f.attemptStarts = make(map[string]time.Time)
+	}
+	f.attemptStarts[network+"/"+addr] = time.Now()
+}
+
+//connectDone - httptrace hook, may be called concurrently when dialing in parallel
+func (f *Ferret) connectDone(network, addr string, err error) {
+	f.attemptsMtx.Lock()
+	defer f.attemptsMtx.Unlock()
+	attempt := ConnectAttempt{Addr: addr, Err: err}
+	if start, ok := f.attemptStarts[network+"/"+addr]; ok {
+		attempt.Duration = time.Since(start)
+	}
+	f.connectAttempts = append(f.connectAttempts, attempt)
+}
@@ -0,0 +1,41 @@
+package ferret
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestConnectAttempts(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	f := NewFerret(WithDNSCache(time.Minute))
+	//The server only listens on 127.0.0.1, so the first address is refused
+	f.dnsCache.store("multi.test", []string{"127.0.0.2", "127.0.0.1"})
+
+	roundTrip(t, f, newRequest(t, http.MethodGet, "http://multi.test:"+port))
+
+	attempts := f.ConnectAttempts()
+	if len(attempts) != 2 {
+		t.Fatalf("ConnectAttempts() = %+v, want 2 attempts", attempts)
+	}
+	if attempts[0].Addr != "127.0.0.2:"+port || attempts[0].Err == nil {
+		t.Errorf("first attempt = %+v, want a failed dial of 127.0.0.2", attempts[0])
+	}
+	if attempts[1].Addr != "127.0.0.1:"+port || attempts[1].Err != nil {
+		t.Errorf("second attempt = %+v, want a successful dial of 127.0.0.1", attempts[1])
+	}
+}
+
+func TestConnectAttemptsSingleAddress(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	f := NewFerret()
+
+	roundTrip(t, f, newRequest(t, http.MethodGet, srv.URL))
+
+	attempts := f.ConnectAttempts()
+	if len(attempts) != 1 || attempts[0].Addr != srv.Listener.Addr().String() || attempts[0].Err != nil {
+		t.Errorf("ConnectAttempts() = %+v, want one successful attempt", attempts)
+	}
+}
@@ -0,0 +1,177 @@
+package ferret
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+//ErrBodyTooLarge - Returned when reading past the limit set by WithMaxBodyBytes
+var ErrBodyTooLarge = errors.New("ferret: response body too large")
+
+//ErrBodyReadTimeout - Returned when a body read stalls for longer than WithBodyReadTimeout allows
+var ErrBodyReadTimeout = errors.New("ferret: response body read timed out")
+
+//guardBody - Install the wrappers enforcing the body limits, kept even by WithoutTiming
+func (f *Ferret) guardBody(resp *http.Response) io.ReadCloser {
+	if resp.Body == nil || resp.StatusCode == http.StatusSwitchingProtocols {
+		//101 bodies are io.ReadWriteClosers and must not be hidden
+		return resp.Body
+	}
+	body := resp.Body
+	if f.bodyReadTimeout > 0 {
+		body = newReadTimeoutBody(body, f.bodyReadTimeout)
+	}
+	if f.maxBodyBytes > 0 {
+		body = &maxBytesBody{ReadCloser: body, remaining: f.maxBodyBytes}
+	}
+	return body
+}
+
+//wrapBody - Install the body guards and the wrappers which observe the response as it is read
+func (f *Ferret) wrapBody(resp *http.Response) io.ReadCloser {
+	if resp.Body == nil || resp.StatusCode == http.StatusSwitchingProtocols {
+		return resp.Body
+	}
+	body := f.guardBody(resp)
+	if f.bodyTap != nil {
+		body = &tapBody{ReadCloser: body, f: f}
+	}
+	if f.captureBodyBytes > 0 {
+		body = &captureBody{ReadCloser: body, f: f}
+	}
+	body = &trailerBody{ReadCloser: body, f: f, resp: resp}
+	return &errBody{ReadCloser: body, f: f}
+}
+
+//errBody - Record the first body read error on the Ferret so Err reports it
+type errBody struct {
+	io.ReadCloser
+	f *Ferret
+}
+
+func (b *errBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.f.err == nil {
+		b.f.err = err
+	}
+	return n, err
+}
+
+//maxBytesBody - Fail reads once more than the allowed number of bytes arrive
+type maxBytesBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *maxBytesBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	//Read one byte past the limit so an exactly sized body still reaches EOF
+	//(comparing against remaining first keeps remaining+1 from overflowing)
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) <= b.remaining {
+		b.remaining -= int64(n)
+		return n, err
+	}
+	n = int(b.remaining)
+	b.remaining = 0
+	b.exceeded = true
+	return n, ErrBodyTooLarge
+}
+
+//trailerBody - Record whether trailers arrived once the body reaches EOF
+type trailerBody struct {
+	io.ReadCloser
+	f    *Ferret
+	resp *http.Response
+}
+
+func (b *trailerBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF && !b.f.hasTrailers && trailersSent(b.resp.Trailer) {
+		b.f.hasTrailers = true
+		b.f.trailerTime = time.Now()
+	}
+	return n, err
+}
+
+//trailersSent - Declared trailer keys are present with nil values until the server actually sends them
+func trailersSent(trailer http.Header) bool {
+	for _, vs := range trailer {
+		if len(vs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+//tapBody - Hand every chunk read from the body to a callback
+type tapBody struct {
+	io.ReadCloser
+	f *Ferret
+}
+
+func (b *tapBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.f.guard(func() { b.f.bodyTap(p[:n]) })
+	}
+	return n, err
+}
+
+//readTimeoutBody - Close the body when a single read stalls, unblocking the reader
+type readTimeoutBody struct {
+	io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+}
+
+func newReadTimeoutBody(body io.ReadCloser, timeout time.Duration) *readTimeoutBody {
+	b := &readTimeoutBody{ReadCloser: body, timeout: timeout}
+	b.timer = time.AfterFunc(timeout, func() { body.Close() })
+	b.timer.Stop()
+	return b
+}
+
+func (b *readTimeoutBody) Read(p []byte) (int, error) {
+	b.timer.Reset(b.timeout)
+	n, err := b.ReadCloser.Read(p)
+	if !b.timer.Stop() {
+		//The watchdog fired and closed the body underneath us
+		return n, ErrBodyReadTimeout
+	}
+	return n, err
+}
+
+func (b *readTimeoutBody) Close() error {
+	b.timer.Stop()
+	return b.ReadCloser.Close()
+}
+
+//captureBody - Keep a copy of the start of the body while passing it on unchanged
+type captureBody struct {
+	io.ReadCloser
+	f *Ferret
+}
+
+func (b *captureBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := b.f.captureBodyBytes - int64(len(b.f.responseBody)); room > 0 && n > 0 {
+		chunk := p[:n]
+		if int64(len(chunk)) > room {
+			chunk = chunk[:room]
+		}
+		b.f.responseBody = append(b.f.responseBody, chunk...)
+	}
+	return n, err
+}
@@ -0,0 +1,177 @@
+package ferret
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+//readAll - Send r through f and drain the body, returning what was read and the read error
+func readAll(t *testing.T, f *Ferret, r *http.Request) ([]byte, error) {
+	t.Helper()
+	resp, err := f.RoundTrip(r)
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func TestTrailers(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		io.WriteString(w, "body")
+		w.Header().Set("X-Checksum", "abc")
+	})
+	f := NewFerret()
+
+	if _, err := readAll(t, f, newRequest(t, "GET", srv.URL)); err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if !f.HasTrailers() {
+		t.Errorf("HasTrailers() = false, want true")
+	}
+	if !f.TrailerTime().After(f.reqEnd) {
+		t.Errorf("TrailerTime() %v is not after the headers arrived %v", f.TrailerTime(), f.reqEnd)
+	}
+}
+
+func TestTrailersDeclaredButNotSent(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		io.WriteString(w, "body")
+	})
+	f := NewFerret()
+
+	if _, err := readAll(t, f, newRequest(t, "GET", srv.URL)); err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if f.HasTrailers() {
+		t.Errorf("HasTrailers() = true for a declared but unsent trailer")
+	}
+	if !f.TrailerTime().IsZero() {
+		t.Errorf("TrailerTime() = %v, want zero", f.TrailerTime())
+	}
+}
+
+func TestWithMaxBodyBytes(t *testing.T) {
+	const limit = 1 << 20
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("x"), 2<<20))
+	})
+	f := NewFerret(WithMaxBodyBytes(limit))
+
+	body, err := readAll(t, f, newRequest(t, "GET", srv.URL))
+	if err != ErrBodyTooLarge {
+		t.Fatalf("read err = %v, want ErrBodyTooLarge", err)
+	}
+	if len(body) != limit {
+		t.Errorf("read %d bytes before failing, want %d", len(body), limit)
+	}
+	if f.Err() != ErrBodyTooLarge {
+		t.Errorf("Err() = %v, want ErrBodyTooLarge", f.Err())
+	}
+}
+
+func TestWithMaxBodyBytesExactSize(t *testing.T) {
+	const limit = 1 << 20
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("x"), limit))
+	})
+	f := NewFerret(WithMaxBodyBytes(limit))
+
+	body, err := readAll(t, f, newRequest(t, "GET", srv.URL))
+	if err != nil || len(body) != limit {
+		t.Fatalf("read (%d bytes, %v), want (%d bytes, nil)", len(body), err, limit)
+	}
+	if f.Err() != nil {
+		t.Errorf("Err() = %v, want nil", f.Err())
+	}
+}
+
+func TestWithResponseBodyTap(t *testing.T) {
+	payload := bytes.Repeat([]byte("ferret"), 10000)
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(payload)
+	})
+	h := sha256.New()
+	f := NewFerret(WithResponseBodyTap(func(p []byte) { h.Write(p) }))
+
+	if _, err := readAll(t, f, newRequest(t, "GET", srv.URL)); err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if got, want := h.Sum(nil), sha256.Sum256(payload); !bytes.Equal(got, want[:]) {
+		t.Errorf("tap digest = %x, want %x", got, want)
+	}
+}
+
+func TestWithBodyReadTimeout(t *testing.T) {
+	const d = 100 * time.Millisecond
+	stall := make(chan struct{})
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("x"))
+		w.(http.Flusher).Flush()
+		<-stall
+	})
+	t.Cleanup(func() { close(stall) }) // runs before the server is closed
+	f := NewFerret(WithBodyReadTimeout(d))
+
+	start := time.Now()
+	body, err := readAll(t, f, newRequest(t, "GET", srv.URL))
+	elapsed := time.Since(start)
+
+	if err != ErrBodyReadTimeout {
+		t.Fatalf("read err = %v, want ErrBodyReadTimeout", err)
+	}
+	if string(body) != "x" {
+		t.Errorf("read %q before the stall, want %q", body, "x")
+	}
+	if elapsed < d || elapsed > 10*d {
+		t.Errorf("read failed after %v, want about %v", elapsed, d)
+	}
+	if f.Err() != ErrBodyReadTimeout {
+		t.Errorf("Err() = %v, want ErrBodyReadTimeout", f.Err())
+	}
+}
+
+func TestWithCaptureResponseBody(t *testing.T) {
+	const payload = `{"status":"ok","region":"us-east-1"}`
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, payload)
+	})
+
+	f := NewFerret(WithCaptureResponseBody(1024))
+	body, err := readAll(t, f, newRequest(t, "GET", srv.URL))
+	if err != nil || string(body) != payload {
+		t.Fatalf("read (%q, %v), want (%q, nil)", body, err, payload)
+	}
+	if got := string(f.ResponseBody()); got != payload {
+		t.Errorf("ResponseBody() = %q, want %q", got, payload)
+	}
+
+	f = NewFerret(WithCaptureResponseBody(8))
+	body, err = readAll(t, f, newRequest(t, "GET", srv.URL))
+	if err != nil || string(body) != payload {
+		t.Fatalf("read (%q, %v), want the whole body despite the cap", body, err)
+	}
+	if got := string(f.ResponseBody()); got != payload[:8] {
+		t.Errorf("ResponseBody() = %q, want %q", got, payload[:8])
+	}
+}
+
+func TestWithMaxBodyBytesNoLimit(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	})
+	f := NewFerret(WithMaxBodyBytes(math.MaxInt64))
+
+	body, err := readAll(t, f, newRequest(t, "GET", srv.URL))
+	if err != nil || string(body) != "hello" {
+		t.Errorf("read (%q, %v), want (%q, nil)", body, err, "hello")
+	}
+}
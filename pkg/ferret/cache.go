@@ -0,0 +1,39 @@
+package ferret
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//cacheStatusHeaders - Headers used by common CDNs to report whether a response came from cache
+var cacheStatusHeaders = []string{"X-Cache", "CF-Cache-Status", "X-Cache-Status"}
+
+//cacheAge - Parse the Age header (RFC 7234 delta-seconds)
+func cacheAge(h http.Header) time.Duration {
+	age, err := strconv.ParseInt(strings.TrimSpace(h.Get("Age")), 10, 64)
+	if err != nil || age < 0 {
+		return 0
+	}
+	return time.Duration(age) * time.Second
+}
+
+//cacheStatus - Reduce values like "Hit from cloudfront" or "MISS, HIT" to "HIT"/"MISS"/...
+func cacheStatus(h http.Header) string {
+	for _, name := range cacheStatusHeaders {
+		v := strings.TrimSpace(h.Get(name))
+		if v == "" {
+			continue
+		}
+		//With several caches in the path the closest one is listed last
+		if i := strings.LastIndex(v, ","); i >= 0 {
+			v = strings.TrimSpace(v[i+1:])
+		}
+		if i := strings.IndexByte(v, ' '); i >= 0 {
+			v = v[:i]
+		}
+		return strings.ToUpper(v)
+	}
+	return ""
+}
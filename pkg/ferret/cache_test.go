@@ -0,0 +1,64 @@
+package ferret
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCacheAge(t *testing.T) {
+	cases := []struct {
+		age  string
+		want time.Duration
+	}{
+		{"120", 120 * time.Second},
+		{" 5 ", 5 * time.Second},
+		{"", 0},
+		{"-1", 0},
+		{"soon", 0},
+		{"1.5", 0},
+	}
+	for _, c := range cases {
+		h := http.Header{"Age": {c.age}}
+		if got := cacheAge(h); got != c.want {
+			t.Errorf("cacheAge(Age: %q) = %v, want %v", c.age, got, c.want)
+		}
+	}
+}
+
+func TestCacheStatus(t *testing.T) {
+	cases := []struct {
+		header http.Header
+		want   string
+	}{
+		{http.Header{"X-Cache": {"HIT"}}, "HIT"},
+		{http.Header{"X-Cache": {"Hit from cloudfront"}}, "HIT"},
+		{http.Header{"X-Cache": {"MISS, HIT"}}, "HIT"},
+		{http.Header{"X-Cache": {"HIT, miss from edge"}}, "MISS"},
+		{http.Header{"Cf-Cache-Status": {"dynamic"}}, "DYNAMIC"},
+		{http.Header{"X-Cache-Status": {"EXPIRED"}}, "EXPIRED"},
+		{http.Header{}, ""},
+	}
+	for _, c := range cases {
+		if got := cacheStatus(c.header); got != c.want {
+			t.Errorf("cacheStatus(%v) = %q, want %q", c.header, got, c.want)
+		}
+	}
+}
+
+func TestCacheHeaders(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Age", "120")
+		w.Header().Set("X-Cache", "HIT")
+	})
+	f := NewFerret()
+
+	roundTrip(t, f, newRequest(t, http.MethodGet, srv.URL))
+
+	if got := f.CacheAge(); got != 120*time.Second {
+		t.Errorf("CacheAge() = %v, want 2m0s", got)
+	}
+	if got := f.CacheStatus(); got != "HIT" {
+		t.Errorf("CacheStatus() = %q, want %q", got, "HIT")
+	}
+}
@@ -0,0 +1,29 @@
+package ferret
+
+import (
+	"net/http"
+	"time"
+)
+
+//NewClient - Create an http.Client whose transport is a new Ferret.
+//Read the timing back through client.Transport.(*Ferret). The Ferret keeps the timing of a
+//single request, so the client must serve one request at a time; use a client per goroutine
+func NewClient(opts ...Option) *http.Client {
+	f := NewFerret(opts...)
+	return &http.Client{Transport: f, Jar: f.cookieJar}
+}
+
+//WithCookieJar - Give clients built by NewClient a cookie jar (the bare transport ignores it)
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(f *Ferret) {
+		f.cookieJar = jar
+	}
+}
+
+//NewClientWithTimeout - Create an http.Client using a new Ferret, limited to total per request.
+//Like NewClient, the client must serve one request at a time
+func NewClientWithTimeout(total time.Duration, opts ...Option) *http.Client {
+	client := NewClient(opts...)
+	client.Timeout = total
+	return client
+}
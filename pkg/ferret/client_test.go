@@ -0,0 +1,69 @@
+package ferret
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewClient(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	client := NewClient()
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	f, ok := client.Transport.(*Ferret)
+	if !ok {
+		t.Fatalf("client.Transport is %T, want *Ferret", client.Transport)
+	}
+	if f.Duration() <= 0 || f.ConnDuration() <= 0 {
+		t.Errorf("Duration() = %v, ConnDuration() = %v, want both positive", f.Duration(), f.ConnDuration())
+	}
+}
+
+func TestNewClientWithTimeout(t *testing.T) {
+	stall := make(chan struct{})
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-stall
+	})
+	t.Cleanup(func() { close(stall) })
+	client := NewClientWithTimeout(50 * time.Millisecond)
+
+	_, err := client.Get(srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "Client.Timeout") {
+		t.Errorf("Get err = %v, want a client timeout", err)
+	}
+}
+
+func TestWithCookieJar(t *testing.T) {
+	var got string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err == nil {
+			got = c.Value
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+	})
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("cookiejar.New: %v", err)
+	}
+	client := NewClient(WithCookieJar(jar))
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if got != "abc" {
+		t.Errorf("second request sent session cookie %q, want %q", got, "abc")
+	}
+}
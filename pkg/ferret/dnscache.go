@@ -0,0 +1,101 @@
+package ferret
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+//maxDNSCacheEntries - Upper bound on the number of hosts held by a dnsCache
+const maxDNSCacheEntries = 1024
+
+//dnsCache - A small concurrency-safe cache of host lookups
+type dnsCache struct {
+	mtx     sync.Mutex
+	ttl     time.Duration
+	entries map[string]dnsCacheEntry
+}
+
+type dnsCacheEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+func newDNSCache(ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		ttl:     ttl,
+		entries: make(map[string]dnsCacheEntry),
+	}
+}
+
+func (c *dnsCache) lookup(host string) ([]string, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	e, ok := c.entries[host]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, host)
+		return nil, false
+	}
+	return e.addrs, true
+}
+
+func (c *dnsCache) store(host string, addrs []string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= maxDNSCacheEntries {
+		for h, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, h)
+			}
+		}
+	}
+	if len(c.entries) >= maxDNSCacheEntries {
+		//Still full, make room by dropping an arbitrary entry
+		for h := range c.entries {
+			delete(c.entries, h)
+			break
+		}
+	}
+	c.entries[host] = dnsCacheEntry{addrs: addrs, expires: now.Add(c.ttl)}
+}
+
+//dialCached - Dial addr, resolving its host through the cache
+func (f *Ferret) dialCached(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return f.dialer.DialContext(ctx, network, addr)
+	}
+
+	addrs, hit := f.dnsCache.lookup(host)
+	f.dnsCacheHit = hit
+	if !hit {
+		resolver := f.dialer.Resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		addrs, err = resolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		f.dnsCache.store(host, addrs)
+	}
+
+	for _, a := range addrs {
+		var cn net.Conn
+		cn, err = f.dialer.DialContext(ctx, network, net.JoinHostPort(a, port))
+		if err == nil {
+			return cn, nil
+		}
+	}
+	return nil, err
+}
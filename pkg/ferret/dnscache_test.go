@@ -0,0 +1,60 @@
+package ferret
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWithDNSCache(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	f := NewFerret(WithDNSCache(time.Minute))
+
+	for i, want := range []bool{false, true} {
+		roundTrip(t, f, newRequest(t, "GET", "http://localhost:"+port))
+		if got := f.DNSCacheHit(); got != want {
+			t.Errorf("request %d: DNSCacheHit() = %v, want %v", i+1, got, want)
+		}
+	}
+}
+
+func TestDNSCacheExpiry(t *testing.T) {
+	c := newDNSCache(10 * time.Millisecond)
+	c.store("host", []string{"127.0.0.1"})
+	if _, ok := c.lookup("host"); !ok {
+		t.Fatalf("lookup straight after store missed")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.lookup("host"); ok {
+		t.Errorf("lookup after ttl hit")
+	}
+}
+
+func TestDNSCacheBounded(t *testing.T) {
+	c := newDNSCache(time.Minute)
+	for i := 0; i < maxDNSCacheEntries+10; i++ {
+		c.store(fmt.Sprintf("host%d", i), []string{"127.0.0.1"})
+	}
+	if len(c.entries) > maxDNSCacheEntries {
+		t.Errorf("cache holds %d entries, want at most %d", len(c.entries), maxDNSCacheEntries)
+	}
+	if _, ok := c.lookup(fmt.Sprintf("host%d", maxDNSCacheEntries+9)); !ok {
+		t.Errorf("most recent entry was evicted")
+	}
+}
+
+func TestDNSCacheFallback(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	f := NewFerret(WithDNSCache(time.Minute))
+	//The server only listens on 127.0.0.1, so the first address is refused
+	f.dnsCache.store("multi.test", []string{"127.0.0.2", "127.0.0.1"})
+
+	roundTrip(t, f, newRequest(t, "GET", "http://multi.test:"+port))
+	if !f.DNSCacheHit() {
+		t.Errorf("DNSCacheHit() = false for a preloaded host")
+	}
+}
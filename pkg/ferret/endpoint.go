@@ -0,0 +1,21 @@
+package ferret
+
+//Endpoint - Something to measure: an identifier, a display name and the URL to request
+type Endpoint struct {
+	ID   string
+	Name string
+	URL  string
+}
+
+//EndpointProvider - A source of endpoints, e.g. the regions of a cloud provider
+type EndpointProvider interface {
+	Endpoints() []Endpoint
+}
+
+//StaticEndpoints - An EndpointProvider serving a fixed list of endpoints
+type StaticEndpoints []Endpoint
+
+//Endpoints - Get the endpoints in the list
+func (s StaticEndpoints) Endpoints() []Endpoint {
+	return s
+}
@@ -0,0 +1,21 @@
+package ferret
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStaticEndpoints(t *testing.T) {
+	want := []Endpoint{
+		{ID: "local", Name: "Local", URL: "http://127.0.0.1:8080/ping"},
+		{ID: "edge", Name: "Edge", URL: "https://edge.example.com/ping"},
+	}
+	var provider EndpointProvider = StaticEndpoints(want)
+
+	if got := provider.Endpoints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Endpoints() = %+v, want %+v", got, want)
+	}
+	if got := StaticEndpoints(nil).Endpoints(); len(got) != 0 {
+		t.Errorf("empty StaticEndpoints returned %+v", got)
+	}
+}
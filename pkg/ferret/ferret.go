@@ -1,23 +1,100 @@
 package ferret
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
 	"net"
 	"net/http"
+	"net/http/httptrace"
+	"sync"
 	"time"
 )
 
 //Ferret - A custom transport which adds timing information to measure request duration
 type Ferret struct {
 	rtp       http.RoundTripper
+	transport *http.Transport
 	dialer    *net.Dialer
 	connStart time.Time
 	connEnd   time.Time
 	reqStart  time.Time
 	reqEnd    time.Time
+	err       error
+
+	redirects     int
+	chainStart    time.Time
+	chainConnTime time.Duration
+	maxRedirects  int
+	collectHops   bool
+	hopDurations  []time.Duration
+
+	correlationIDKey interface{}
+	correlationID    string
+
+	labels map[string]string
+
+	reqHeaderBytes  int64
+	respHeaderBytes int64
+	cacheAge        time.Duration
+	cacheStatus     string
+	notModified     bool
+	protocol        string
+
+	dnsCache    *dnsCache
+	dnsCacheHit bool
+
+	unixSocket  string
+	connectTo   string
+	dialNetwork string
+	network     string
+	localAddr   string
+
+	hasTrailers bool
+	trailerTime time.Time
+
+	requestIDHeader string
+	requestID       string
+
+	userAgents    []string
+	nextUserAgent uint64
+
+	gotConn      time.Time
+	firstByte    time.Time
+	wait100Start time.Time
+	got100       time.Time
+	tlsError     *TLSErrorDetail
+	serverName   string
+
+	attemptsMtx     sync.Mutex
+	attemptStarts   map[string]time.Time
+	connectAttempts []ConnectAttempt
+
+	connectHook func(net.Conn)
+
+	maxBodyBytes    int64
+	bodyTap         func([]byte)
+	bodyReadTimeout time.Duration
+
+	captureBodyBytes int64
+	responseBody     []byte
+
+	statsd       StatsDClient
+	statsdPrefix string
+
+	panicHandler func(interface{})
+
+	slowThreshold time.Duration
+	slowLog       *log.Logger
+
+	cookieJar http.CookieJar
+
+	passThrough bool
 }
 
 //NewFerret - Create a new Ferret (custom transport)
-func NewFerret() *Ferret {
+func NewFerret(opts ...Option) *Ferret {
 
 	f := &Ferret{
 		dialer: &net.Dialer{
@@ -25,30 +102,160 @@ func NewFerret() *Ferret {
 			KeepAlive: -1 * time.Second,
 		},
 	}
-	f.rtp = &http.Transport{
+	f.transport = &http.Transport{
 		Proxy:               http.ProxyFromEnvironment,
-		Dial:                f.dial,
+		DialContext:         f.dial,
 		TLSHandshakeTimeout: 10 * time.Second,
 		DisableKeepAlives:   true,
 	}
+	f.rtp = f.transport
+	for _, opt := range opts {
+		opt(f)
+	}
 	return f
 }
 
 //RoundTrip - Meausure the full time from start to finish
 func (f *Ferret) RoundTrip(r *http.Request) (*http.Response, error) {
+	if f.passThrough {
+		resp, err := f.rtp.RoundTrip(r)
+		if resp != nil {
+			resp.Body = f.guardBody(resp)
+		}
+		return resp, err
+	}
 	f.reqStart = time.Now()
+	if r.Response == nil {
+		//First hop of a (possibly redirected) request
+		f.redirects = 0
+		f.chainStart = f.reqStart
+		f.chainConnTime = 0
+		f.hopDurations = nil
+	} else {
+		f.redirects++
+	}
+	//Measured before ferret stamps its own headers
+	f.reqHeaderBytes = headerSize(r.Header)
+	if f.correlationIDKey != nil {
+		r = f.propagateCorrelationID(r)
+	}
+	if f.requestIDHeader != "" {
+		r = f.stampRequestID(r)
+	}
+	if len(f.userAgents) > 0 && r.Header.Get("User-Agent") == "" {
+		r = withHeader(r, "User-Agent", f.rotateUserAgent())
+	}
+	f.respHeaderBytes = 0
+	f.cacheAge, f.cacheStatus = 0, ""
+	f.notModified = false
+	f.responseBody = nil
+	f.protocol = ""
+	f.hasTrailers = false
+	f.trailerTime = time.Time{}
+	f.gotConn, f.firstByte = time.Time{}, time.Time{}
+	f.wait100Start, f.got100 = time.Time{}, time.Time{}
+	f.tlsError, f.serverName = nil, ""
+	f.resetConnectAttempts()
+	r = r.WithContext(httptrace.WithClientTrace(r.Context(), f.clientTrace()))
 	resp, err := f.rtp.RoundTrip(r)
 	f.reqEnd = time.Now()
+	f.err = err
+	if err == nil && f.firstByte.IsZero() {
+		//Bodyless responses (HEAD, 204, 304) still have headers; fall back to their arrival
+		//if GotFirstResponseByte did not fire
+		f.firstByte = f.reqEnd
+	}
+	if f.collectHops {
+		f.hopDurations = append(f.hopDurations, f.Duration())
+	}
+	if f.slowLog != nil && f.IsSlow(f.slowThreshold) {
+		f.logSlowRequest(r, err)
+	}
+	if f.statsd != nil {
+		f.guard(func() { f.emitStatsD(r, resp, err) })
+	}
+	if resp != nil {
+		f.respHeaderBytes = headerSize(resp.Header)
+		f.cacheAge = cacheAge(resp.Header)
+		f.cacheStatus = cacheStatus(resp.Header)
+		f.notModified = resp.StatusCode == http.StatusNotModified
+		f.protocol = resp.Proto
+		resp.Body = f.wrapBody(resp)
+	}
 	return resp, err
 }
 
-func (f *Ferret) dial(network, addr string) (net.Conn, error) {
+func (f *Ferret) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if f.passThrough {
+		return f.dialTarget(ctx, network, addr)
+	}
 	f.connStart = time.Now()
-	cn, err := f.dialer.Dial(network, addr)
+	f.dnsCacheHit = false
+	f.network, f.localAddr = "", ""
+	cn, err := f.dialTarget(ctx, network, addr)
 	f.connEnd = time.Now()
+	if err == nil {
+		f.network = addrFamily(cn.RemoteAddr())
+		f.localAddr = cn.LocalAddr().String()
+	}
+	if err == nil && f.connectHook != nil {
+		f.guard(func() { f.connectHook(cn) })
+	}
 	return cn, err
 }
 
+//guard - Run user supplied callback code, recovering panics if WithCallbackPanicHandler is set
+func (f *Ferret) guard(fn func()) {
+	if f.panicHandler != nil {
+		defer func() {
+			if v := recover(); v != nil {
+				f.panicHandler(v)
+			}
+		}()
+	}
+	fn()
+}
+
+//dialTarget - Pick how to reach addr based on the configured options
+func (f *Ferret) dialTarget(ctx context.Context, network, addr string) (net.Conn, error) {
+	if f.unixSocket != "" {
+		return f.dialer.DialContext(ctx, "unix", f.unixSocket)
+	}
+	if f.connectTo != "" {
+		addr = f.connectTo
+	}
+	if f.dialNetwork != "" {
+		network = f.dialNetwork
+	}
+	if f.dnsCache != nil {
+		return f.dialCached(ctx, network, addr)
+	}
+	return f.dialer.DialContext(ctx, network, addr)
+}
+
+//addrFamily - Name the network ("tcp4", "tcp6", "unix", ...) a connection was made over
+func addrFamily(addr net.Addr) string {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return addr.Network()
+	}
+	if tcp.IP.To4() != nil {
+		return "tcp4"
+	}
+	return "tcp6"
+}
+
+//headerSize - Approximate the serialized size of h ("Key: Value\r\n" per value)
+func headerSize(h http.Header) int64 {
+	var n int64
+	for k, vs := range h {
+		for _, v := range vs {
+			n += int64(len(k) + len(": ") + len(v) + len("\r\n"))
+		}
+	}
+	return n
+}
+
 //ReqDuration - Get the time spent making the request
 func (f *Ferret) ReqDuration() time.Duration {
 	return f.Duration() - f.ConnDuration()
@@ -63,3 +270,197 @@ func (f *Ferret) ConnDuration() time.Duration {
 func (f *Ferret) Duration() time.Duration {
 	return f.reqEnd.Sub(f.reqStart)
 }
+
+//BackendLatency - Get the time from holding an established (TLS) connection to the first
+//response byte, i.e. request write plus server processing without any connection setup
+func (f *Ferret) BackendLatency() time.Duration {
+	if f.gotConn.IsZero() || f.firstByte.IsZero() {
+		return 0
+	}
+	return f.firstByte.Sub(f.gotConn)
+}
+
+//DurationsEqual - Report whether every measured duration of f and other is within tolerance,
+//ignoring when the requests actually happened
+func (f *Ferret) DurationsEqual(other *Ferret, tolerance time.Duration) bool {
+	pairs := [][2]time.Duration{
+		{f.ConnDuration(), other.ConnDuration()},
+		{f.ReqDuration(), other.ReqDuration()},
+		{f.Duration(), other.Duration()},
+		{f.BackendLatency(), other.BackendLatency()},
+		{f.Continue100Duration(), other.Continue100Duration()},
+	}
+	for _, p := range pairs {
+		diff := p[0] - p[1]
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+//IsSlow - Report whether the overall time spent exceeded threshold
+func (f *Ferret) IsSlow(threshold time.Duration) bool {
+	return f.Duration() > threshold
+}
+
+//RedirectCount - Get the number of redirects followed to reach the final response
+func (f *Ferret) RedirectCount() int {
+	return f.redirects
+}
+
+//RedirectPolicy - Get a CheckRedirect func for http.Client enforcing the limit set by WithRedirectPolicy,
+//a limit of zero or less follows every redirect
+func (f *Ferret) RedirectPolicy() func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if f.maxRedirects > 0 && len(via) >= f.maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", f.maxRedirects)
+		}
+		return nil
+	}
+}
+
+//HopDurations - Get the duration of every hop of the last redirect chain (see WithRedirectPolicy)
+func (f *Ferret) HopDurations() []time.Duration {
+	return f.hopDurations
+}
+
+//TotalDurationIncludingRedirects - Get the overall time spent across every hop of a redirect chain
+func (f *Ferret) TotalDurationIncludingRedirects() time.Duration {
+	return f.reqEnd.Sub(f.chainStart)
+}
+
+//ConnDurationIncludingRedirects - Get the cumulative connection setup time (DNS, connect and TLS handshake)
+//across every hop of a redirect chain
+func (f *Ferret) ConnDurationIncludingRedirects() time.Duration {
+	return f.chainConnTime
+}
+
+//CorrelationID - Get the correlation ID propagated from the request context
+func (f *Ferret) CorrelationID() string {
+	return f.correlationID
+}
+
+//Labels - Get a copy of the static labels attached to this Ferret
+func (f *Ferret) Labels() map[string]string {
+	if f.labels == nil {
+		return nil
+	}
+	labels := make(map[string]string, len(f.labels))
+	for k, v := range f.labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+//RequestHeaderBytes - Get the serialized size of the caller supplied request headers, excluding
+//those ferret adds (correlation ID, request ID, rotated User-Agent)
+func (f *Ferret) RequestHeaderBytes() int64 {
+	return f.reqHeaderBytes
+}
+
+//ResponseHeaderBytes - Get the serialized size of the response headers
+func (f *Ferret) ResponseHeaderBytes() int64 {
+	return f.respHeaderBytes
+}
+
+//DNSCacheHit - Report whether the last connection resolved its host from the DNS cache
+func (f *Ferret) DNSCacheHit() bool {
+	return f.dnsCacheHit
+}
+
+//HasTrailers - Report whether the response carried trailers (known once the body is read)
+func (f *Ferret) HasTrailers() bool {
+	return f.hasTrailers
+}
+
+//TrailerTime - Get the time the trailers became available at the end of the body
+func (f *Ferret) TrailerTime() time.Time {
+	return f.trailerTime
+}
+
+//RequestID - Get the request ID sent with the last request
+func (f *Ferret) RequestID() string {
+	return f.requestID
+}
+
+//Continue100Duration - Get the time between sending "Expect: 100-continue" headers and
+//receiving the 100 response (requires an ExpectContinueTimeout on the transport)
+func (f *Ferret) Continue100Duration() time.Duration {
+	if f.wait100Start.IsZero() || f.got100.IsZero() {
+		return 0
+	}
+	return f.got100.Sub(f.wait100Start)
+}
+
+//Network - Get the network ("tcp4", "tcp6", "unix") of the last connection
+func (f *Ferret) Network() string {
+	return f.network
+}
+
+//TLSError - Get the classified TLS handshake failure of the last request, if any
+func (f *Ferret) TLSError() *TLSErrorDetail {
+	return f.tlsError
+}
+
+//CacheAge - Get how long the response had been held in a cache (Age header)
+func (f *Ferret) CacheAge() time.Duration {
+	return f.cacheAge
+}
+
+//CacheStatus - Get the cache status reported by the CDN ("HIT", "MISS", ...), empty if unknown
+func (f *Ferret) CacheStatus() string {
+	return f.cacheStatus
+}
+
+//LocalAddr - Get the local address (interface and port) of the last connection
+func (f *Ferret) LocalAddr() string {
+	return f.localAddr
+}
+
+//NotModified - Report whether the server answered a conditional request with 304 Not Modified
+func (f *Ferret) NotModified() bool {
+	return f.notModified
+}
+
+//Protocol - Get the protocol of the last response ("HTTP/1.1", "HTTP/2.0")
+func (f *Ferret) Protocol() string {
+	return f.protocol
+}
+
+//Err - Get the error returned by the last round trip, if any, or else the first error
+//hit while reading its response body (e.g. ErrBodyTooLarge or ErrBodyReadTimeout)
+func (f *Ferret) Err() error {
+	return f.err
+}
+
+//ErrorChain - Get the message of every layer of the last error, outermost first
+func (f *Ferret) ErrorChain() []string {
+	var chain []string
+	for err := f.err; err != nil; err = errors.Unwrap(err) {
+		chain = append(chain, err.Error())
+	}
+	return chain
+}
+
+//ErrorType - Get the Go type of the last error (e.g. "*net.OpError"), empty if there was none
+func (f *Ferret) ErrorType() string {
+	if f.err == nil {
+		return ""
+	}
+	return fmt.Sprintf("%T", f.err)
+}
+
+//ServerName - Get the TLS server name (SNI) sent during the last handshake
+func (f *Ferret) ServerName() string {
+	return f.serverName
+}
+
+//ResponseBody - Get the captured start of the response body (see WithCaptureResponseBody),
+//complete only once the caller has read that far
+func (f *Ferret) ResponseBody() []byte {
+	return f.responseBody
+}
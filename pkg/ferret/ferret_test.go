@@ -0,0 +1,397 @@
+package ferret
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, h http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newRequest(t *testing.T, method, url string) *http.Request {
+	t.Helper()
+	r, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	return r
+}
+
+//roundTrip - Send r through f and close the body
+func roundTrip(t *testing.T, f *Ferret, r *http.Request) *http.Response {
+	t.Helper()
+	resp, err := f.RoundTrip(r)
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+//redirectHandler - Redirect /0 -> /1 -> ... -> /hops, then answer 200
+func redirectHandler(hops int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscanf(r.URL.Path, "/%d", &n)
+		if n < hops {
+			http.Redirect(w, r, fmt.Sprintf("/%d", n+1), http.StatusFound)
+		}
+	}
+}
+
+func TestRedirectCount(t *testing.T) {
+	srv := newTestServer(t, redirectHandler(3))
+	f := NewFerret()
+
+	resp, err := (&http.Client{Transport: f}).Get(srv.URL + "/0")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := f.RedirectCount(); got != 3 {
+		t.Errorf("RedirectCount() = %d, want 3", got)
+	}
+	if f.TotalDurationIncludingRedirects() < f.Duration() {
+		t.Errorf("TotalDurationIncludingRedirects() %v < Duration() %v", f.TotalDurationIncludingRedirects(), f.Duration())
+	}
+	if f.ConnDurationIncludingRedirects() < f.ConnDuration() {
+		t.Errorf("ConnDurationIncludingRedirects() %v < ConnDuration() %v", f.ConnDurationIncludingRedirects(), f.ConnDuration())
+	}
+
+	//A new request through the same Ferret starts a new chain
+	roundTrip(t, f, newRequest(t, "GET", srv.URL+"/3"))
+	if got := f.RedirectCount(); got != 0 {
+		t.Errorf("RedirectCount() after a direct request = %d, want 0", got)
+	}
+}
+
+func TestHeaderBytes(t *testing.T) {
+	big := strings.Repeat("x", 1000)
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Big-1", big)
+		w.Header().Set("X-Big-2", big)
+	})
+	f := NewFerret()
+
+	r := newRequest(t, "GET", srv.URL)
+	r.Header.Set("X-Req", "value")
+	roundTrip(t, f, r)
+
+	if got, want := f.RequestHeaderBytes(), int64(len("X-Req: value\r\n")); got != want {
+		t.Errorf("RequestHeaderBytes() = %d, want %d", got, want)
+	}
+	if got, min := f.ResponseHeaderBytes(), int64(2*(len("X-Big-1: ")+len(big)+2)); got < min {
+		t.Errorf("ResponseHeaderBytes() = %d, want at least %d", got, min)
+	}
+}
+
+func TestIsSlow(t *testing.T) {
+	start := time.Now()
+	f := &Ferret{reqStart: start, reqEnd: start.Add(200 * time.Millisecond)}
+
+	if !f.IsSlow(100 * time.Millisecond) {
+		t.Errorf("IsSlow(100ms) = false for a 200ms request")
+	}
+	if f.IsSlow(200 * time.Millisecond) {
+		t.Errorf("IsSlow(200ms) = true for a 200ms request")
+	}
+}
+
+func TestContinue100Duration(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		io.Copy(io.Discard, r.Body) // the server sends 100 Continue on the first body read
+	})
+	f := NewFerret(WithTransportTimeouts(TransportTimeouts{ExpectContinueTimeout: time.Second}))
+
+	r, err := http.NewRequest(http.MethodPut, srv.URL, strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	r.Header.Set("Expect", "100-continue")
+	roundTrip(t, f, r)
+
+	if got := f.Continue100Duration(); got < delay || got > f.Duration() {
+		t.Errorf("Continue100Duration = %v, want between %v and %v", got, delay, f.Duration())
+	}
+}
+
+func TestRedirectPolicy(t *testing.T) {
+	srv := newTestServer(t, redirectHandler(5))
+	f := NewFerret(WithRedirectPolicy(3, true))
+	client := &http.Client{Transport: f, CheckRedirect: f.RedirectPolicy()}
+
+	if _, err := client.Get(srv.URL + "/0"); err == nil || !strings.Contains(err.Error(), "stopped after 3 redirects") {
+		t.Fatalf("Get err = %v, want the redirect limit error", err)
+	}
+	if got := len(f.HopDurations()); got != 3 {
+		t.Errorf("recorded %d hops, want 3", got)
+	}
+}
+
+func TestRedirectPolicyUnlimited(t *testing.T) {
+	srv := newTestServer(t, redirectHandler(5))
+	f := NewFerret(WithRedirectPolicy(0, true))
+	client := &http.Client{Transport: f, CheckRedirect: f.RedirectPolicy()}
+
+	resp, err := client.Get(srv.URL + "/0")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+	if got := len(f.HopDurations()); got != 6 {
+		t.Errorf("recorded %d hops, want 6", got)
+	}
+}
+
+func TestLocalAddr(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	f := NewFerret()
+
+	roundTrip(t, f, newRequest(t, http.MethodGet, srv.URL))
+
+	host, _, err := net.SplitHostPort(f.LocalAddr())
+	if err != nil {
+		t.Fatalf("LocalAddr() = %q: %v", f.LocalAddr(), err)
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		t.Errorf("LocalAddr() = %q, want a loopback address", f.LocalAddr())
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	const etag = `"v1"`
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+	})
+	f := NewFerret()
+
+	roundTrip(t, f, newRequest(t, http.MethodGet, srv.URL))
+	if f.NotModified() {
+		t.Errorf("NotModified() = true for an unconditional request")
+	}
+
+	r := newRequest(t, http.MethodGet, srv.URL)
+	r.Header.Set("If-None-Match", etag)
+	roundTrip(t, f, r)
+	if !f.NotModified() {
+		t.Errorf("NotModified() = false for a 304 response")
+	}
+}
+
+func TestBackendLatency(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+	})
+	f := NewFerret()
+
+	roundTrip(t, f, newRequest(t, http.MethodGet, srv.URL))
+
+	if got := f.BackendLatency(); got < delay || got > f.Duration() {
+		t.Errorf("BackendLatency() = %v, want between %v and Duration() %v", got, delay, f.Duration())
+	}
+}
+
+//timedFerret - Build a Ferret which connected for conn and took total overall, starting at start
+func timedFerret(start time.Time, conn, total time.Duration) *Ferret {
+	return &Ferret{
+		reqStart:  start,
+		connStart: start,
+		connEnd:   start.Add(conn),
+		reqEnd:    start.Add(total),
+	}
+}
+
+func TestDurationsEqual(t *testing.T) {
+	now := time.Now()
+	a := timedFerret(now, 10*time.Millisecond, 50*time.Millisecond)
+	cases := []struct {
+		name  string
+		other *Ferret
+		want  bool
+	}{
+		{"equal at another time", timedFerret(now.Add(time.Hour), 10*time.Millisecond, 50*time.Millisecond), true},
+		{"within tolerance", timedFerret(now, 12*time.Millisecond, 53*time.Millisecond), true},
+		{"connect out of tolerance", timedFerret(now, 20*time.Millisecond, 50*time.Millisecond), false},
+		{"total out of tolerance", timedFerret(now, 10*time.Millisecond, 60*time.Millisecond), false},
+	}
+	for _, c := range cases {
+		if got := a.DurationsEqual(c.other, 5*time.Millisecond); got != c.want {
+			t.Errorf("%s: DurationsEqual = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestErrorChain(t *testing.T) {
+	inner := errors.New("connection reset")
+	f := &Ferret{err: fmt.Errorf("read body: %w", fmt.Errorf("conn: %w", inner))}
+
+	want := []string{"read body: conn: connection reset", "conn: connection reset", "connection reset"}
+	if got := f.ErrorChain(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ErrorChain() = %q, want %q", got, want)
+	}
+	if got := (&Ferret{}).ErrorChain(); got != nil {
+		t.Errorf("ErrorChain() without an error = %q, want nil", got)
+	}
+}
+
+func TestErrorType(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	srv.Close()
+	f := NewFerret()
+
+	if _, err := f.RoundTrip(newRequest(t, http.MethodGet, srv.URL)); err == nil {
+		t.Fatalf("RoundTrip to a closed server succeeded")
+	}
+	if got := f.ErrorType(); got != "*net.OpError" {
+		t.Errorf("ErrorType() = %q, want %q", got, "*net.OpError")
+	}
+	if len(f.ErrorChain()) < 2 {
+		t.Errorf("ErrorChain() = %q, want the dial error and its cause", f.ErrorChain())
+	}
+}
+
+func benchmarkRoundTrip(b *testing.B, opts ...Option) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	f := NewFerret(opts...)
+	r, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		b.Fatalf("NewRequest: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resp, err := f.RoundTrip(r)
+		if err != nil {
+			b.Fatalf("RoundTrip: %v", err)
+		}
+		resp.Body.Close()
+	}
+}
+
+func BenchmarkRoundTrip(b *testing.B) {
+	benchmarkRoundTrip(b)
+}
+
+func BenchmarkRoundTripWithoutTiming(b *testing.B) {
+	benchmarkRoundTrip(b, WithoutTiming())
+}
+
+func TestHeadBackendLatency(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set("Content-Length", "1024")
+	})
+	f := NewFerret()
+
+	body, err := readAll(t, f, newRequest(t, http.MethodHead, srv.URL))
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if len(body) != 0 {
+		t.Errorf("HEAD returned %d body bytes, want none", len(body))
+	}
+	if f.BackendLatency() <= 0 {
+		t.Errorf("BackendLatency() = %v for HEAD, want > 0", f.BackendLatency())
+	}
+}
+
+func TestConnDurationIncludingRedirectsTLS(t *testing.T) {
+	const handshakeDelay = 20 * time.Millisecond
+	srv := httptest.NewUnstartedServer(redirectHandler(3))
+	srv.TLS = &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			time.Sleep(handshakeDelay)
+			return nil, nil
+		},
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	f := NewFerret()
+	f.transport.TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+
+	resp, err := (&http.Client{Transport: f}).Get(srv.URL + "/0")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	//Every one of the 4 hops handshakes on a new connection
+	if got, want := f.ConnDurationIncludingRedirects(), 4*handshakeDelay; got < want {
+		t.Errorf("ConnDurationIncludingRedirects() = %v, want at least %v of TLS handshakes", got, want)
+	}
+}
+
+func TestRequestHeaderBytesExcludesStampedHeaders(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	f := NewFerret(WithRequestIDHeader(""), WithUserAgentRotation([]string{"agent/1"}))
+
+	r := newRequest(t, "GET", srv.URL)
+	r.Header.Set("X-Req", "value")
+	roundTrip(t, f, r)
+
+	if got, want := f.RequestHeaderBytes(), int64(len("X-Req: value\r\n")); got != want {
+		t.Errorf("RequestHeaderBytes() = %d, want %d (caller headers only)", got, want)
+	}
+}
+
+//roundTripFunc - A RoundTripper stub which fires no trace hooks unless fn does
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+func TestFirstByteFallback(t *testing.T) {
+	f := NewFerret()
+	f.rtp = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		httptrace.ContextClientTrace(r.Context()).GotConn(httptrace.GotConnInfo{})
+		time.Sleep(time.Millisecond)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	})
+
+	roundTrip(t, f, newRequest(t, http.MethodHead, "http://stub.test/"))
+
+	if !f.firstByte.Equal(f.reqEnd) {
+		t.Errorf("firstByte = %v, want the response arrival %v", f.firstByte, f.reqEnd)
+	}
+	if f.BackendLatency() <= 0 {
+		t.Errorf("BackendLatency() = %v, want > 0", f.BackendLatency())
+	}
+}
+
+func TestFirstByteFallbackSkippedOnError(t *testing.T) {
+	f := NewFerret()
+	f.rtp = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, errors.New("stub failure")
+	})
+
+	if _, err := f.RoundTrip(newRequest(t, http.MethodHead, "http://stub.test/")); err == nil {
+		t.Fatalf("RoundTrip succeeded, want the stub failure")
+	}
+	if !f.firstByte.IsZero() {
+		t.Errorf("firstByte = %v after a failed request, want zero", f.firstByte)
+	}
+}
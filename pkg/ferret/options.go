@@ -0,0 +1,276 @@
+package ferret
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+//CorrelationIDHeader - Header used to propagate the correlation ID on outbound requests
+const CorrelationIDHeader = "X-Correlation-ID"
+
+//DefaultRequestIDHeader - Header used by WithRequestIDHeader when no name is given
+const DefaultRequestIDHeader = "X-Request-ID"
+
+//Option - Configure optional Ferret behaviour
+type Option func(*Ferret)
+
+//TransportTimeouts - Timeouts applied to the underlying http.Transport,
+//zero values leave the transport default in place
+type TransportTimeouts struct {
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	ExpectContinueTimeout time.Duration
+	IdleConnTimeout       time.Duration
+}
+
+//WithCorrelationIDKey - Read a correlation ID from the request context using key
+//and echo it on the Ferret and in the X-Correlation-ID request header
+func WithCorrelationIDKey(key interface{}) Option {
+	return func(f *Ferret) {
+		f.correlationIDKey = key
+	}
+}
+
+//WithStaticLabels - Attach static metadata (environment, service name, ...) to every measurement
+func WithStaticLabels(labels map[string]string) Option {
+	return func(f *Ferret) {
+		f.labels = make(map[string]string, len(labels))
+		for k, v := range labels {
+			f.labels[k] = v
+		}
+	}
+}
+
+//WithDNSCache - Cache host lookups for ttl so repeated connections through
+//this Ferret skip the resolver
+func WithDNSCache(ttl time.Duration) Option {
+	return func(f *Ferret) {
+		f.dnsCache = newDNSCache(ttl)
+	}
+}
+
+//WithUnixSocket - Dial the unix socket at path regardless of the URL host,
+//so requests like http://unix/health reach a local service
+func WithUnixSocket(path string) Option {
+	return func(f *Ferret) {
+		f.unixSocket = path
+	}
+}
+
+//WithRequestIDHeader - Stamp a random request ID in headerName (X-Request-ID if empty)
+//on every request which does not already carry one
+func WithRequestIDHeader(headerName string) Option {
+	return func(f *Ferret) {
+		if headerName == "" {
+			headerName = DefaultRequestIDHeader
+		}
+		f.requestIDHeader = headerName
+	}
+}
+
+//WithTransportTimeouts - Set the transport timeouts in one go
+func WithTransportTimeouts(t TransportTimeouts) Option {
+	return func(f *Ferret) {
+		if t.TLSHandshakeTimeout != 0 {
+			f.transport.TLSHandshakeTimeout = t.TLSHandshakeTimeout
+		}
+		if t.ResponseHeaderTimeout != 0 {
+			f.transport.ResponseHeaderTimeout = t.ResponseHeaderTimeout
+		}
+		if t.ExpectContinueTimeout != 0 {
+			f.transport.ExpectContinueTimeout = t.ExpectContinueTimeout
+		}
+		if t.IdleConnTimeout != 0 {
+			f.transport.IdleConnTimeout = t.IdleConnTimeout
+		}
+	}
+}
+
+//WithResponseHeaderTimeout - Fail requests whose response headers do not arrive within d
+func WithResponseHeaderTimeout(d time.Duration) Option {
+	return func(f *Ferret) {
+		f.transport.ResponseHeaderTimeout = d
+	}
+}
+
+//WithMaxResponseHeaderBytes - Fail requests whose response headers exceed n bytes
+func WithMaxResponseHeaderBytes(n int64) Option {
+	return func(f *Ferret) {
+		f.transport.MaxResponseHeaderBytes = n
+	}
+}
+
+//WithConnectTo - Connect to hostPort instead of the URL host (like curl --connect-to),
+//the Host header and TLS server name still come from the request URL
+func WithConnectTo(hostPort string) Option {
+	return func(f *Ferret) {
+		f.connectTo = hostPort
+	}
+}
+
+//WithControl - Set the dialer's Control func, e.g. to enable TCP Fast Open or other
+//socket options before the connection is established
+func WithControl(fn func(network, address string, c syscall.RawConn) error) Option {
+	return func(f *Ferret) {
+		f.dialer.Control = func(network, address string, c syscall.RawConn) (err error) {
+			f.guard(func() { err = fn(network, address, c) })
+			return err
+		}
+	}
+}
+
+//WithNetwork - Dial using network ("tcp4", "tcp6" or "tcp") to compare IPv4 and IPv6 latency
+func WithNetwork(network string) Option {
+	return func(f *Ferret) {
+		f.dialNetwork = network
+	}
+}
+
+//WithServerName - Send name as the TLS server name (SNI) and verify the certificate against it,
+//independently of the Host header; combine with WithConnectTo to test a specific backend
+func WithServerName(name string) Option {
+	return func(f *Ferret) {
+		if f.transport.TLSClientConfig == nil {
+			f.transport.TLSClientConfig = &tls.Config{}
+		} else {
+			f.transport.TLSClientConfig = f.transport.TLSClientConfig.Clone()
+		}
+		f.transport.TLSClientConfig.ServerName = name
+	}
+}
+
+//WithConnectHook - Inspect each newly dialed connection before the transport uses it.
+//The hook runs outside the measured connect time; it must not close the connection
+//or keep a reference to it beyond the call
+func WithConnectHook(fn func(net.Conn)) Option {
+	return func(f *Ferret) {
+		f.connectHook = fn
+	}
+}
+
+//WithMaxBodyBytes - Fail reads of response bodies larger than n bytes with ErrBodyTooLarge
+func WithMaxBodyBytes(n int64) Option {
+	return func(f *Ferret) {
+		f.maxBodyBytes = n
+	}
+}
+
+//WithResponseBodyTap - Call fn with each chunk of the response body as the caller reads it.
+//fn runs on the reader's goroutine and must not retain p after returning
+func WithResponseBodyTap(fn func(p []byte)) Option {
+	return func(f *Ferret) {
+		f.bodyTap = fn
+	}
+}
+
+//WithRedirectPolicy - Limit redirect chains to max requests (zero or less for no limit) and optionally
+//record each hop's duration. CheckRedirect lives on http.Client, so assign RedirectPolicy() to the client
+//to enforce the limit
+func WithRedirectPolicy(max int, collectHops bool) Option {
+	return func(f *Ferret) {
+		f.maxRedirects = max
+		f.collectHops = collectHops
+	}
+}
+
+//WithCaptureResponseBody - Keep a copy of up to maxBytes of the response body as the caller reads it,
+//larger bodies keep streaming but are not captured past the limit
+func WithCaptureResponseBody(maxBytes int64) Option {
+	return func(f *Ferret) {
+		f.captureBodyBytes = maxBytes
+	}
+}
+
+//WithBodyReadTimeout - Fail a response body read with ErrBodyReadTimeout when it stalls for longer than d
+func WithBodyReadTimeout(d time.Duration) Option {
+	return func(f *Ferret) {
+		f.bodyReadTimeout = d
+	}
+}
+
+//WithDisableCompression - Stop the transport from requesting gzip so bodies arrive as sent on the wire
+func WithDisableCompression() Option {
+	return func(f *Ferret) {
+		f.transport.DisableCompression = true
+	}
+}
+
+//WithUserAgentRotation - Cycle through agents round-robin for requests without a User-Agent
+func WithUserAgentRotation(agents []string) Option {
+	return func(f *Ferret) {
+		f.userAgents = append([]string(nil), agents...)
+	}
+}
+
+//WithCallbackPanicHandler - Recover panics raised by user callbacks (connect hook, dialer
+//control, body tap, StatsD client) and pass the recovered value to fn instead of crashing
+func WithCallbackPanicHandler(fn func(interface{})) Option {
+	return func(f *Ferret) {
+		f.panicHandler = fn
+	}
+}
+
+//WithForceHTTP1 - Pin requests to HTTP/1.1. The transport's custom dialer already keeps
+//net/http from negotiating HTTP/2, so this only states that choice explicitly
+func WithForceHTTP1() Option {
+	return func(f *Ferret) {
+		f.transport.ForceAttemptHTTP2 = false
+		f.transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+}
+
+//WithoutTiming - Pass requests straight through without recording anything or installing
+//httptrace hooks; useful as a baseline when measuring ferret's own overhead.
+//Dialer and transport options, WithMaxBodyBytes and WithBodyReadTimeout still apply. Header
+//stamping (correlation ID, request ID, User-Agent rotation), WithConnectHook, the body tap and
+//capture, hop collection, the slow request log and StatsD do nothing
+func WithoutTiming() Option {
+	return func(f *Ferret) {
+		f.passThrough = true
+	}
+}
+
+func (f *Ferret) propagateCorrelationID(r *http.Request) *http.Request {
+	f.correlationID = ""
+	id, ok := r.Context().Value(f.correlationIDKey).(string)
+	if !ok || id == "" {
+		return r
+	}
+	f.correlationID = id
+	if r.Header.Get(CorrelationIDHeader) != "" {
+		return r
+	}
+	return withHeader(r, CorrelationIDHeader, id)
+}
+
+func (f *Ferret) stampRequestID(r *http.Request) *http.Request {
+	if id := r.Header.Get(f.requestIDHeader); id != "" {
+		f.requestID = id
+		return r
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		f.requestID = ""
+		return r
+	}
+	f.requestID = hex.EncodeToString(b[:])
+	return withHeader(r, f.requestIDHeader, f.requestID)
+}
+
+func (f *Ferret) rotateUserAgent() string {
+	n := atomic.AddUint64(&f.nextUserAgent, 1) - 1
+	return f.userAgents[n%uint64(len(f.userAgents))]
+}
+
+//withHeader - RoundTrippers must not modify the caller's request, so set headers on a clone
+func withHeader(r *http.Request, key, value string) *http.Request {
+	r = r.Clone(r.Context())
+	r.Header.Set(key, value)
+	return r
+}
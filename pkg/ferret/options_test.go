@@ -0,0 +1,422 @@
+package ferret
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type correlationKey struct{}
+
+func TestWithCorrelationIDKey(t *testing.T) {
+	var header string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(CorrelationIDHeader)
+	})
+	f := NewFerret(WithCorrelationIDKey(correlationKey{}))
+
+	ctx := context.WithValue(context.Background(), correlationKey{}, "abc-123")
+	r := newRequest(t, "GET", srv.URL).WithContext(ctx)
+	roundTrip(t, f, r)
+
+	if header != "abc-123" {
+		t.Errorf("%s header = %q, want %q", CorrelationIDHeader, header, "abc-123")
+	}
+	if got := f.CorrelationID(); got != "abc-123" {
+		t.Errorf("CorrelationID() = %q, want %q", got, "abc-123")
+	}
+	if r.Header.Get(CorrelationIDHeader) != "" {
+		t.Errorf("caller's request was modified")
+	}
+
+	//An explicit header wins over the context value
+	r = newRequest(t, "GET", srv.URL).WithContext(ctx)
+	r.Header.Set(CorrelationIDHeader, "explicit")
+	roundTrip(t, f, r)
+	if header != "explicit" {
+		t.Errorf("%s header = %q, want %q", CorrelationIDHeader, header, "explicit")
+	}
+}
+
+func TestWithStaticLabels(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	labels := map[string]string{"env": "prod", "service": "api"}
+	f := NewFerret(WithStaticLabels(labels))
+	labels["env"] = "changed"
+
+	roundTrip(t, f, newRequest(t, "GET", srv.URL))
+
+	got := f.Labels()
+	if len(got) != 2 || got["env"] != "prod" || got["service"] != "api" {
+		t.Errorf("Labels() = %v, want env=prod service=api", got)
+	}
+	got["env"] = "mutated"
+	if f.Labels()["env"] != "prod" {
+		t.Errorf("Labels() returned the internal map")
+	}
+	if NewFerret().Labels() != nil {
+		t.Errorf("Labels() without WithStaticLabels should be nil")
+	}
+}
+
+func TestWithUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ferret.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "healthy")
+	}))
+	srv.Listener = l
+	srv.Start()
+	defer srv.Close()
+
+	f := NewFerret(WithUnixSocket(path))
+	resp, err := f.RoundTrip(newRequest(t, "GET", "http://unix/health"))
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "healthy" {
+		t.Errorf("body = %q, want %q", body, "healthy")
+	}
+	if f.ConnDuration() <= 0 {
+		t.Errorf("ConnDuration() = %v, want > 0", f.ConnDuration())
+	}
+	if got := f.Network(); got != "unix" {
+		t.Errorf("Network() = %q, want unix", got)
+	}
+}
+
+func TestWithRequestIDHeader(t *testing.T) {
+	var mtx sync.Mutex
+	seen := make(map[string]bool)
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		seen[r.Header.Get(DefaultRequestIDHeader)] = true
+		mtx.Unlock()
+	})
+
+	const requests = 20
+	ids := make([]string, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			//A Ferret times one request at a time, so each goroutine has its own
+			f := NewFerret(WithRequestIDHeader(""))
+			resp, err := f.RoundTrip(newRequest(t, "GET", srv.URL))
+			if err != nil {
+				t.Errorf("RoundTrip: %v", err)
+				return
+			}
+			resp.Body.Close()
+			ids[i] = f.RequestID()
+		}(i)
+	}
+	wg.Wait()
+
+	if len(seen) != requests {
+		t.Errorf("server saw %d distinct request IDs, want %d", len(seen), requests)
+	}
+	for _, id := range ids {
+		if len(id) != 32 || !seen[id] {
+			t.Errorf("RequestID() = %q was not a 32 character ID sent to the server", id)
+		}
+	}
+}
+
+func TestWithRequestIDHeaderPreservesExisting(t *testing.T) {
+	var got string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-Trace")
+	})
+	f := NewFerret(WithRequestIDHeader("X-Trace"))
+
+	r := newRequest(t, "GET", srv.URL)
+	r.Header.Set("X-Trace", "mine")
+	roundTrip(t, f, r)
+
+	if got != "mine" || f.RequestID() != "mine" {
+		t.Errorf("header = %q, RequestID() = %q, want both %q", got, f.RequestID(), "mine")
+	}
+}
+
+func TestWithTransportTimeouts(t *testing.T) {
+	f := NewFerret(WithTransportTimeouts(TransportTimeouts{
+		ResponseHeaderTimeout: 2 * time.Second,
+		ExpectContinueTimeout: 3 * time.Second,
+		IdleConnTimeout:       4 * time.Second,
+	}))
+
+	tr := f.transport
+	if tr.TLSHandshakeTimeout != 10*time.Second {
+		t.Errorf("TLSHandshakeTimeout = %v, want the 10s default to be kept", tr.TLSHandshakeTimeout)
+	}
+	if tr.ResponseHeaderTimeout != 2*time.Second {
+		t.Errorf("ResponseHeaderTimeout = %v, want 2s", tr.ResponseHeaderTimeout)
+	}
+	if tr.ExpectContinueTimeout != 3*time.Second {
+		t.Errorf("ExpectContinueTimeout = %v, want 3s", tr.ExpectContinueTimeout)
+	}
+	if tr.IdleConnTimeout != 4*time.Second {
+		t.Errorf("IdleConnTimeout = %v, want 4s", tr.IdleConnTimeout)
+	}
+
+	f = NewFerret(WithTransportTimeouts(TransportTimeouts{TLSHandshakeTimeout: time.Second}))
+	if f.transport.TLSHandshakeTimeout != time.Second {
+		t.Errorf("TLSHandshakeTimeout = %v, want 1s", f.transport.TLSHandshakeTimeout)
+	}
+}
+
+func TestWithResponseHeaderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	defer close(release)
+	f := NewFerret(WithResponseHeaderTimeout(50 * time.Millisecond))
+
+	_, err := f.RoundTrip(newRequest(t, "GET", srv.URL))
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Fatalf("RoundTrip error = %v, want a timeout", err)
+	}
+	if f.connStart.IsZero() || f.ConnDuration() <= 0 {
+		t.Errorf("connect timing was not captured")
+	}
+	if !f.firstByte.IsZero() {
+		t.Errorf("first byte recorded for a request that never got a response")
+	}
+}
+
+func TestWithMaxResponseHeaderBytes(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Huge", strings.Repeat("x", 64<<10))
+	})
+	f := NewFerret(WithMaxResponseHeaderBytes(4 << 10))
+
+	_, err := f.RoundTrip(newRequest(t, "GET", srv.URL))
+	if err == nil || !strings.Contains(err.Error(), "response headers exceeded") {
+		t.Fatalf("RoundTrip error = %v, want the header limit to be exceeded", err)
+	}
+	if f.Err() != err {
+		t.Errorf("Err() = %v, want %v", f.Err(), err)
+	}
+	if f.ConnDuration() <= 0 {
+		t.Errorf("connect timing was not captured")
+	}
+}
+
+func TestWithConnectHook(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	var remote string
+	f := NewFerret(WithConnectHook(func(cn net.Conn) {
+		remote = cn.RemoteAddr().String()
+	}))
+
+	roundTrip(t, f, newRequest(t, http.MethodGet, srv.URL))
+
+	if want := srv.Listener.Addr().String(); remote != want {
+		t.Errorf("hook saw RemoteAddr %q, want %q", remote, want)
+	}
+}
+
+func TestWithConnectTo(t *testing.T) {
+	var host string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+	})
+	f := NewFerret(WithConnectTo(srv.Listener.Addr().String()))
+
+	roundTrip(t, f, newRequest(t, http.MethodGet, "http://backend.example:8080/"))
+
+	if host != "backend.example:8080" {
+		t.Errorf("server saw Host %q, want %q", host, "backend.example:8080")
+	}
+}
+
+func TestWithControl(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	var network, address string
+	f := NewFerret(WithControl(func(n, a string, c syscall.RawConn) error {
+		network, address = n, a
+		return nil
+	}))
+
+	roundTrip(t, f, newRequest(t, http.MethodGet, srv.URL))
+
+	if network != "tcp4" || address != srv.Listener.Addr().String() {
+		t.Errorf("Control saw (%q, %q), want (%q, %q)", network, address, "tcp4", srv.Listener.Addr().String())
+	}
+}
+
+func TestWithNetwork(t *testing.T) {
+	ln, err := net.Listen("tcp", "[::]:0")
+	if err != nil {
+		t.Skipf("no dual-stack listener: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Listener.Close()
+	srv.Listener = ln
+	srv.Start()
+	t.Cleanup(srv.Close)
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	if cn, err := net.Dial("tcp6", net.JoinHostPort("::1", port)); err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	} else {
+		cn.Close()
+	}
+
+	cases := []struct {
+		network string
+		addrs   []string // the other family first, so only the option picks the wanted one
+		ipv4    bool
+	}{
+		{"tcp4", []string{"::1", "127.0.0.1"}, true},
+		{"tcp6", []string{"127.0.0.1", "::1"}, false},
+	}
+	for _, c := range cases {
+		f := NewFerret(WithDNSCache(time.Minute), WithNetwork(c.network))
+		f.dnsCache.store("dual.test", c.addrs)
+
+		roundTrip(t, f, newRequest(t, http.MethodGet, "http://dual.test:"+port))
+
+		if got := f.Network(); got != c.network {
+			t.Errorf("%s: Network() = %q", c.network, got)
+		}
+		host, _, _ := net.SplitHostPort(f.LocalAddr())
+		if ip := net.ParseIP(host); ip == nil || (ip.To4() != nil) != c.ipv4 {
+			t.Errorf("%s: LocalAddr() = %q in the wrong address family", c.network, f.LocalAddr())
+		}
+	}
+}
+
+func TestWithDisableCompression(t *testing.T) {
+	var acceptEncoding []string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = append(acceptEncoding, r.Header.Get("Accept-Encoding"))
+	})
+
+	roundTrip(t, NewFerret(), newRequest(t, http.MethodGet, srv.URL))
+	roundTrip(t, NewFerret(WithDisableCompression()), newRequest(t, http.MethodGet, srv.URL))
+
+	if acceptEncoding[0] != "gzip" {
+		t.Errorf("default Accept-Encoding = %q, want %q", acceptEncoding[0], "gzip")
+	}
+	if acceptEncoding[1] != "" {
+		t.Errorf("Accept-Encoding with WithDisableCompression = %q, want none", acceptEncoding[1])
+	}
+}
+
+func TestWithUserAgentRotation(t *testing.T) {
+	var seen []string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.UserAgent())
+	})
+	f := NewFerret(WithUserAgentRotation([]string{"a/1", "b/2"}))
+
+	for i := 0; i < 3; i++ {
+		roundTrip(t, f, newRequest(t, http.MethodGet, srv.URL))
+	}
+	explicit := newRequest(t, http.MethodGet, srv.URL)
+	explicit.Header.Set("User-Agent", "mine/1")
+	roundTrip(t, f, explicit)
+
+	if want := []string{"a/1", "b/2", "a/1", "mine/1"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("User-Agents = %v, want %v", seen, want)
+	}
+}
+
+func TestWithCallbackPanicHandler(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})
+	var recovered []interface{}
+	f := NewFerret(
+		WithCallbackPanicHandler(func(v interface{}) { recovered = append(recovered, v) }),
+		WithConnectHook(func(net.Conn) { panic("hook") }),
+		WithResponseBodyTap(func([]byte) { panic("tap") }),
+	)
+
+	body, err := readAll(t, f, newRequest(t, http.MethodGet, srv.URL))
+	if err != nil || string(body) != "ok" {
+		t.Fatalf("read (%q, %v), want (%q, nil)", body, err, "ok")
+	}
+	if want := []interface{}{"hook", "tap"}; !reflect.DeepEqual(recovered, want) {
+		t.Errorf("recovered %v, want %v", recovered, want)
+	}
+}
+
+func TestWithForceHTTP1(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	cases := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"default", nil, "HTTP/2.0"},
+		{"WithForceHTTP1", []Option{WithForceHTTP1()}, "HTTP/1.1"},
+	}
+	for _, c := range cases {
+		f := NewFerret(c.opts...)
+		f.transport.TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+		//The custom dialer alone keeps HTTP/2 off; opt back in so the option has something to override
+		f.transport.ForceAttemptHTTP2 = true
+
+		roundTrip(t, f, newRequest(t, http.MethodGet, srv.URL))
+
+		if got := f.Protocol(); got != c.want {
+			t.Errorf("%s: Protocol() = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestWithoutTiming(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	f := NewFerret(WithoutTiming())
+
+	resp := roundTrip(t, f, newRequest(t, http.MethodGet, srv.URL))
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if f.Duration() != 0 || f.ConnDuration() != 0 {
+		t.Errorf("Duration() = %v, ConnDuration() = %v, want nothing recorded", f.Duration(), f.ConnDuration())
+	}
+}
+
+func TestWithoutTimingKeepsBodyGuards(t *testing.T) {
+	var requestID string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get(DefaultRequestIDHeader)
+		io.WriteString(w, "too long")
+	})
+	f := NewFerret(WithoutTiming(), WithMaxBodyBytes(3), WithRequestIDHeader(""))
+
+	if _, err := readAll(t, f, newRequest(t, http.MethodGet, srv.URL)); err != ErrBodyTooLarge {
+		t.Errorf("read err = %v, want ErrBodyTooLarge", err)
+	}
+	if requestID != "" || f.RequestID() != "" {
+		t.Errorf("request ID %q stamped in pass-through mode", requestID)
+	}
+	if f.Err() != nil {
+		t.Errorf("Err() = %v, want nothing recorded", f.Err())
+	}
+}
@@ -0,0 +1,43 @@
+package ferret
+
+import (
+	"time"
+)
+
+//Profile - A coherent set of timeouts for WithTimeoutProfile
+type Profile int
+
+const (
+	//ProfileFast - Aggressive: 1s dial, 1s TLS handshake, 1s response header (~3s worst case)
+	ProfileFast Profile = iota
+	//ProfileBalanced - 2s dial, 5s TLS handshake, 10s response header
+	ProfileBalanced
+	//ProfilePatient - 10s dial, 15s TLS handshake, 30s response header
+	ProfilePatient
+)
+
+type profileTimeouts struct {
+	dial           time.Duration
+	tlsHandshake   time.Duration
+	responseHeader time.Duration
+}
+
+var profiles = map[Profile]profileTimeouts{
+	ProfileFast:     {dial: 1 * time.Second, tlsHandshake: 1 * time.Second, responseHeader: 1 * time.Second},
+	ProfileBalanced: {dial: 2 * time.Second, tlsHandshake: 5 * time.Second, responseHeader: 10 * time.Second},
+	ProfilePatient:  {dial: 10 * time.Second, tlsHandshake: 15 * time.Second, responseHeader: 30 * time.Second},
+}
+
+//WithTimeoutProfile - Set the dial, TLS handshake and response header timeouts from a preset,
+//unknown profiles leave the timeouts untouched
+func WithTimeoutProfile(p Profile) Option {
+	return func(f *Ferret) {
+		t, ok := profiles[p]
+		if !ok {
+			return
+		}
+		f.dialer.Timeout = t.dial
+		f.transport.TLSHandshakeTimeout = t.tlsHandshake
+		f.transport.ResponseHeaderTimeout = t.responseHeader
+	}
+}
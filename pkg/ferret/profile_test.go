@@ -0,0 +1,38 @@
+package ferret
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithTimeoutProfile(t *testing.T) {
+	cases := []struct {
+		profile                        Profile
+		dial, tlsHandshake, respHeader time.Duration
+	}{
+		{ProfileFast, time.Second, time.Second, time.Second},
+		{ProfileBalanced, 2 * time.Second, 5 * time.Second, 10 * time.Second},
+		{ProfilePatient, 10 * time.Second, 15 * time.Second, 30 * time.Second},
+	}
+	for _, c := range cases {
+		f := NewFerret(WithTimeoutProfile(c.profile))
+		if f.dialer.Timeout != c.dial {
+			t.Errorf("profile %d: dial timeout = %v, want %v", c.profile, f.dialer.Timeout, c.dial)
+		}
+		if f.transport.TLSHandshakeTimeout != c.tlsHandshake {
+			t.Errorf("profile %d: TLS handshake timeout = %v, want %v", c.profile, f.transport.TLSHandshakeTimeout, c.tlsHandshake)
+		}
+		if f.transport.ResponseHeaderTimeout != c.respHeader {
+			t.Errorf("profile %d: response header timeout = %v, want %v", c.profile, f.transport.ResponseHeaderTimeout, c.respHeader)
+		}
+	}
+}
+
+func TestWithTimeoutProfileUnknown(t *testing.T) {
+	want := NewFerret()
+	f := NewFerret(WithTimeoutProfile(Profile(42)))
+
+	if f.dialer.Timeout != want.dialer.Timeout || f.transport.ResponseHeaderTimeout != want.transport.ResponseHeaderTimeout {
+		t.Errorf("unknown profile changed the timeouts")
+	}
+}
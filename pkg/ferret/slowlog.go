@@ -0,0 +1,55 @@
+package ferret
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+//WithSlowRequestLog - Log a JSON record with the timing breakdown of every request
+//slower than threshold; fast requests are not logged and cost nothing extra.
+//Create logger with no flags or prefix (log.New(w, "", 0)) so each line is valid JSON
+func WithSlowRequestLog(threshold time.Duration, logger *log.Logger) Option {
+	return func(f *Ferret) {
+		f.slowThreshold = threshold
+		f.slowLog = logger
+	}
+}
+
+type slowRequest struct {
+	Level     string  `json:"level"`
+	Msg       string  `json:"msg"`
+	Method    string  `json:"method"`
+	URL       string  `json:"url"`
+	ConnMs    float64 `json:"conn_ms"`
+	RequestMs float64 `json:"request_ms"`
+	BackendMs float64 `json:"backend_ms"`
+	TotalMs   float64 `json:"total_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+func (f *Ferret) logSlowRequest(r *http.Request, err error) {
+	rec := slowRequest{
+		Level:     "warn",
+		Msg:       "slow request",
+		Method:    r.Method,
+		URL:       r.URL.String(),
+		ConnMs:    milliseconds(f.ConnDuration()),
+		RequestMs: milliseconds(f.ReqDuration()),
+		BackendMs: milliseconds(f.BackendLatency()),
+		TotalMs:   milliseconds(f.Duration()),
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	b, jerr := json.Marshal(rec)
+	if jerr != nil {
+		return
+	}
+	f.slowLog.Print(string(b))
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
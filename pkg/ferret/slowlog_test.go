@@ -0,0 +1,36 @@
+package ferret
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithSlowRequestLog(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(60 * time.Millisecond)
+		}
+	})
+	var out bytes.Buffer
+	f := NewFerret(WithSlowRequestLog(50*time.Millisecond, log.New(&out, "", 0)))
+
+	roundTrip(t, f, newRequest(t, http.MethodGet, srv.URL+"/fast"))
+	roundTrip(t, f, newRequest(t, http.MethodGet, srv.URL+"/slow"))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("logged %d lines, want only the slow request:\n%s", len(lines), out.String())
+	}
+	var rec slowRequest
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatalf("log line %q is not JSON: %v", lines[0], err)
+	}
+	if rec.URL != srv.URL+"/slow" || rec.Method != http.MethodGet || rec.TotalMs < 50 {
+		t.Errorf("logged %+v, want the GET of /slow taking at least 50ms", rec)
+	}
+}
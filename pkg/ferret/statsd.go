@@ -0,0 +1,48 @@
+package ferret
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//StatsDClient - The subset of a StatsD/DogStatsD client used by WithStatsD
+type StatsDClient interface {
+	Timing(name string, d time.Duration, tags []string)
+	Incr(name string, tags []string)
+}
+
+//WithStatsD - Push connect/request/total timings and a request counter to client,
+//tagged with method, host and status
+func WithStatsD(client StatsDClient, prefix string) Option {
+	return func(f *Ferret) {
+		f.statsd = client
+		f.statsdPrefix = prefix
+	}
+}
+
+func (f *Ferret) emitStatsD(r *http.Request, resp *http.Response, err error) {
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	tags := []string{
+		"method:" + r.Method,
+		"host:" + r.URL.Hostname(),
+		"status:" + status,
+	}
+
+	f.statsd.Incr(f.statsdName("requests"), tags)
+	if err == nil {
+		f.statsd.Timing(f.statsdName("connect"), f.ConnDuration(), tags)
+		f.statsd.Timing(f.statsdName("request"), f.ReqDuration(), tags)
+	}
+	f.statsd.Timing(f.statsdName("total"), f.Duration(), tags)
+}
+
+func (f *Ferret) statsdName(name string) string {
+	if f.statsdPrefix == "" {
+		return name
+	}
+	return f.statsdPrefix + "." + name
+}
@@ -0,0 +1,64 @@
+package ferret
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+//fakeStatsD - Record the metric names and tags sent to it
+type fakeStatsD struct {
+	names []string
+	tags  [][]string
+}
+
+func (s *fakeStatsD) Timing(name string, d time.Duration, tags []string) {
+	s.names = append(s.names, "timing:"+name)
+	s.tags = append(s.tags, tags)
+}
+
+func (s *fakeStatsD) Incr(name string, tags []string) {
+	s.names = append(s.names, "incr:"+name)
+	s.tags = append(s.tags, tags)
+}
+
+func TestWithStatsD(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	client := &fakeStatsD{}
+	f := NewFerret(WithStatsD(client, "ferret"))
+
+	roundTrip(t, f, newRequest(t, http.MethodGet, srv.URL))
+
+	wantNames := []string{"incr:ferret.requests", "timing:ferret.connect", "timing:ferret.request", "timing:ferret.total"}
+	if !reflect.DeepEqual(client.names, wantNames) {
+		t.Errorf("metrics = %v, want %v", client.names, wantNames)
+	}
+	wantTags := []string{"method:GET", "host:127.0.0.1", "status:204"}
+	for i, tags := range client.tags {
+		if !reflect.DeepEqual(tags, wantTags) {
+			t.Errorf("%s tags = %v, want %v", client.names[i], tags, wantTags)
+		}
+	}
+}
+
+func TestWithStatsDError(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	srv.Close()
+	client := &fakeStatsD{}
+	f := NewFerret(WithStatsD(client, ""))
+
+	if _, err := f.RoundTrip(newRequest(t, http.MethodGet, srv.URL)); err == nil {
+		t.Fatalf("RoundTrip to a closed server succeeded")
+	}
+
+	wantNames := []string{"incr:requests", "timing:total"}
+	if !reflect.DeepEqual(client.names, wantNames) {
+		t.Errorf("metrics = %v, want %v", client.names, wantNames)
+	}
+	if got := client.tags[0][2]; got != "status:error" {
+		t.Errorf("status tag = %q, want %q", got, "status:error")
+	}
+}
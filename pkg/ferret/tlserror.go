@@ -0,0 +1,67 @@
+package ferret
+
+import (
+	"crypto/x509"
+	"errors"
+)
+
+//TLSErrorKind - Why a TLS handshake failed
+type TLSErrorKind int
+
+const (
+	//TLSErrorOther - The handshake failed for a reason not classified below
+	TLSErrorOther TLSErrorKind = iota
+	//TLSErrorExpired - The certificate is expired or not yet valid
+	TLSErrorExpired
+	//TLSErrorHostnameMismatch - The certificate is not valid for the requested host
+	TLSErrorHostnameMismatch
+	//TLSErrorUnknownAuthority - The certificate is signed by an untrusted authority
+	TLSErrorUnknownAuthority
+)
+
+//String - Describe the kind of failure
+func (k TLSErrorKind) String() string {
+	switch k {
+	case TLSErrorExpired:
+		return "expired"
+	case TLSErrorHostnameMismatch:
+		return "hostname mismatch"
+	case TLSErrorUnknownAuthority:
+		return "unknown authority"
+	default:
+		return "other"
+	}
+}
+
+//TLSErrorDetail - A classified TLS handshake failure
+type TLSErrorDetail struct {
+	Kind TLSErrorKind
+	Err  error
+}
+
+//Error - Describe the failure, prefixed with its classification
+func (d *TLSErrorDetail) Error() string {
+	return "tls " + d.Kind.String() + ": " + d.Err.Error()
+}
+
+//Unwrap - Get the underlying handshake error
+func (d *TLSErrorDetail) Unwrap() error {
+	return d.Err
+}
+
+func classifyTLSError(err error) *TLSErrorDetail {
+	detail := &TLSErrorDetail{Kind: TLSErrorOther, Err: err}
+
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var authority x509.UnknownAuthorityError
+	switch {
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		detail.Kind = TLSErrorExpired
+	case errors.As(err, &hostname):
+		detail.Kind = TLSErrorHostnameMismatch
+	case errors.As(err, &authority):
+		detail.Kind = TLSErrorUnknownAuthority
+	}
+	return detail
+}
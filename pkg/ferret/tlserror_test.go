@@ -0,0 +1,86 @@
+package ferret
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+//newTLSServer - Start a TLS test server which does not log the handshake failures the tests provoke
+func newTLSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTLSErrorHostnameMismatch(t *testing.T) {
+	srv := newTLSServer(t)
+	f := NewFerret(WithConnectTo(srv.Listener.Addr().String()))
+	f.transport.TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+
+	//The test certificate covers example.com and the loopback IPs, but not other names
+	if _, err := f.RoundTrip(newRequest(t, http.MethodGet, "https://ferret.test/")); err == nil {
+		t.Fatalf("RoundTrip succeeded against a certificate for another host")
+	}
+	if f.TLSError() == nil || f.TLSError().Kind != TLSErrorHostnameMismatch {
+		t.Errorf("TLSError() = %v, want a hostname mismatch", f.TLSError())
+	}
+}
+
+func TestTLSErrorUnknownAuthority(t *testing.T) {
+	srv := newTLSServer(t)
+	f := NewFerret()
+
+	if _, err := f.RoundTrip(newRequest(t, http.MethodGet, srv.URL)); err == nil {
+		t.Fatalf("RoundTrip succeeded against an untrusted certificate")
+	}
+	if f.TLSError() == nil || f.TLSError().Kind != TLSErrorUnknownAuthority {
+		t.Errorf("TLSError() = %v, want an unknown authority", f.TLSError())
+	}
+}
+
+func TestClassifyTLSError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want TLSErrorKind
+	}{
+		{fmt.Errorf("tls: failed to verify certificate: %w", x509.CertificateInvalidError{Reason: x509.Expired}), TLSErrorExpired},
+		{x509.CertificateInvalidError{Reason: x509.NotAuthorizedToSign}, TLSErrorOther},
+		{x509.HostnameError{Host: "ferret.test"}, TLSErrorHostnameMismatch},
+		{x509.UnknownAuthorityError{}, TLSErrorUnknownAuthority},
+		{errors.New("tls: handshake failure"), TLSErrorOther},
+	}
+	for _, c := range cases {
+		detail := classifyTLSError(c.err)
+		if detail.Kind != c.want {
+			t.Errorf("classifyTLSError(%v).Kind = %v, want %v", c.err, detail.Kind, c.want)
+		}
+		if !errors.Is(detail, c.err) {
+			t.Errorf("classifyTLSError(%v) does not unwrap to the original error", c.err)
+		}
+	}
+}
+
+func TestWithServerName(t *testing.T) {
+	srv := newTLSServer(t)
+	f := NewFerret(WithServerName("example.com"))
+	f.transport.TLSClientConfig.RootCAs = srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	//The URL names the server by IP, the certificate is only checked against example.com
+	roundTrip(t, f, newRequest(t, http.MethodGet, srv.URL))
+
+	if got := f.ServerName(); got != "example.com" {
+		t.Errorf("ServerName() = %q, want %q", got, "example.com")
+	}
+	if f.TLSError() != nil {
+		t.Errorf("TLSError() = %v, want nil", f.TLSError())
+	}
+}
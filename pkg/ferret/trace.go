@@ -0,0 +1,37 @@
+package ferret
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"time"
+)
+
+//clientTrace - The httptrace hooks used to time events inside the transport
+func (f *Ferret) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		ConnectStart: f.connectStart,
+		ConnectDone:  f.connectDone,
+		GotConn: func(info httptrace.GotConnInfo) {
+			f.gotConn = time.Now()
+			if !info.Reused && !f.connStart.IsZero() {
+				//Dial plus TLS handshake of this hop's new connection
+				f.chainConnTime += f.gotConn.Sub(f.connStart)
+			}
+		},
+		GotFirstResponseByte: func() {
+			f.firstByte = time.Now()
+		},
+		Wait100Continue: func() {
+			f.wait100Start = time.Now()
+		},
+		Got100Continue: func() {
+			f.got100 = time.Now()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			f.serverName = state.ServerName
+			if err != nil {
+				f.tlsError = classifyTLSError(err)
+			}
+		},
+	}
+}